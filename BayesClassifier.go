@@ -1,71 +1,131 @@
-package main 
+package classifier
 
 import (
-	"os"
 	"fmt"
 	"io"
+	"encoding/binary"
 	"encoding/gob"
+	"hash/fnv"
 	"math"
+	"math/rand"
+	"sort"
+	"sync/atomic"
 )
 
+// The file the command line tool stores the Bayes classifier in.
+const DefaultBayesModel = "BayesClassifier.gob"
+
+func init() {
+	Register("NBC", Backend{
+		New: func(cfg KmerConfig) (Classifier, error) {
+			return NewBayesClassifier(cfg)
+		},
+		Model: DefaultBayesModel,
+	})
+}
+
+// A BayesClassifier may predict from several goroutines at once, as long as
+// none of them changes it meanwhile (with Learn(), Unlearn(), Merge(), 
+// SetModel(), SetBootstrap() or ReadFrom()).
 type BayesClassifier struct{
 	Classes 	[]Class
 	data 		map[Class]*BayesClassData
-	globalData	map[string]int
+	globalData	*WordCounts
+	lineages	map[Class]Lineage
+	config		KmerConfig
+	options		BayesOptions
 	learned 	int
-	seen 		int
+	// The number of predictions made, counted atomically.
+	seen 		int64
+	// The bootstrap used by Predict(), see SetBootstrap().
+	bootstrap	BootstrapOptions
+	// The Bernoulli score of every class for a sequence without words, see
+	// prepare().
+	absent		map[Class]float64
 }
 
 type FormatBayesClassifier struct{
 	Classes 	[]Class
 	Data 		map[Class]*BayesClassData
-	GlobalData	map[string]int
+	GlobalData	*WordCounts
+	Lineages	map[Class]Lineage
+	Config		KmerConfig
 	Learned 	int
 	Seen 		int
+	// The zero value, for classifiers stored before models could be 
+	// chosen, is the legacy model.
+	Options		BayesOptions
 }
 
+// BayesClassData holds what the classifier knows of a class: the number of
+// its species containing each word (Freq) and the number of its species 
+// (Sum), for the legacy, Bernoulli and RDP models, and the number of times 
+// each word occurs in its species (Counts) and their sum (Total), for the 
+// multinomial model. Classes stored before counts were kept have no Counts.
 type BayesClassData struct{
-	Freq		map[string]int
+	Freq		map[Word]int
 	Sum			int
+	Counts		map[Word]int
+	Total		int
 }
 
-//BayesLearnData() learns data from raw data, stores the classifier in file, 
-//and return the pointer to the classifier.
-func BayesLearnData(d RawData) (*BayesClassifier) {
+// NewBayesClassifier() returns an empty Bayes classifier using the given 
+// k-mer settings.
+func NewBayesClassifier(cfg KmerConfig) (*BayesClassifier, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
 	bc := new(BayesClassifier)
-	bc.Classes = make([]Class, len(d.classes))
-	bc.globalData = make(map[string]int)
+	bc.Classes = make([]Class, 0)
+	bc.data = make(map[Class]*BayesClassData)
+	bc.config = cfg
+	bc.options = DefaultBayesOptions
+	bc.globalData = NewWordCounts(cfg)
+	bc.lineages = make(map[Class]Lineage)
 	bc.learned = 0
 	bc.seen = 0
-	var classes []Class = d.classes
-	bc.Classes = classes
-	bc.GenerateData(d)
-	bc.GenerateGlobalData(d)
-	//bc.BCWriteToFile()
+	bc.SetBootstrap(DefaultBootstrapOptions)
+	return bc, nil
+}
 
-	return bc
+//BayesLearnData() learns data from raw data, and return the pointer to the 
+//classifier.
+func BayesLearnData(d *RawData) (*BayesClassifier, error) {
+	bc, err := NewBayesClassifier(d.config)
+	if err != nil {
+		return nil, err
+	}
+	if err := bc.Learn(d); err != nil {
+		return nil, err
+	}
+	return bc, nil
 }
 
+// Learn() adds the species of a data set to the classifier. The words of 
+// the data must have been generated with the settings of the classifier.
+func (bc *BayesClassifier) Learn(d *RawData) error {
+	if d.config != bc.config {
+		return ErrConfigMismatch
+	}
+	bc.GenerateData(d)
+	bc.GenerateGlobalData(d)
+	bc.prepare()
+	return nil
+}
 
-func (bc *BayesClassifier) GenerateGlobalData(d RawData) {
+func (bc *BayesClassifier) GenerateGlobalData(d *RawData) {
 	n := len(d.species)
 	for i := 0; i < n; i++ {
 		length := len(d.species[i].Words)
 		for j := 0; j < length; j++ {
-			bc.globalData[d.species[i].Words[j]]++
+			bc.globalData.Add(d.species[i].Words[j], 1)
 		}
 	}
 }
 
 
 //GenerateData() generates the map of class to class data.
-func (bc *BayesClassifier) GenerateData(d RawData) {
-	length := len(d.classes)
-	bc.data = make(map[Class]*BayesClassData, length)
-	for _, class := range d.classes {
-		bc.data[class] = newBayesClassData()
-	}
-
+func (bc *BayesClassifier) GenerateData(d *RawData) {
 	for i := 0; i < len(d.species); i++  {
 		bc.UpdateData(d.species[i])
 	}
@@ -73,98 +133,495 @@ func (bc *BayesClassifier) GenerateData(d RawData) {
 
 func newBayesClassData() *BayesClassData {
 	return &BayesClassData{
-		Freq:	make(map[string]int),
+		Freq:	make(map[Word]int),
 		Sum:	0,
+		Counts:	make(map[Word]int),
 	}
 }
 
-// UpdateData() updates bayes class data based on a single species data.
+// UpdateData() updates bayes class data based on a single species data. A
+// class the classifier has not seen yet is added.
 func (bc *BayesClassifier) UpdateData(species *Species) {
 	//var temp *BayesClassData = bc.data[species.class]
+	if _, isExist := bc.data[species.Class]; !isExist {
+		bc.data[species.Class] = newBayesClassData()
+		bc.Classes = append(bc.Classes, species.Class)
+	}
 
+	tempData := bc.data[species.Class]
 	for i := 0; i < len(species.Words); i++ {
-		bc.data[species.Class].Freq[species.Words[i]]++
+		tempData.Freq[species.Words[i]]++
+	}
+	tempData.Sum++
+	if tempData.Counts != nil {
+		f := species.Features()
+		for i, word := range f.Words {
+			tempData.Counts[word] += int(f.Count(i))
+			tempData.Total += int(f.Count(i))
+		}
+	}
+	bc.absent = nil
+	if bc.lineages == nil {
+		bc.lineages = make(map[Class]Lineage)
+	}
+	if _, isExist := bc.lineages[species.Class]; !isExist {
+		bc.lineages[species.Class] = species.Lineage
 	}
-	bc.data[species.Class].Sum++
 	bc.learned++
 
 }
 
-//Store a Bayes classifier to a .gob file.
-func (bc *BayesClassifier) BCWriteToFile() {
-	file, err := os.Create("BayesClassifier.gob")
-	if err != nil {
-		fmt.Println("Error1: There is a problem when writing Bayes " +
-					"classifier to a file!")
-		os.Exit(1)
+// Unlearn() removes the species of a data set from the classifier, as if 
+// it had never learned them. The words of the data must have been generated
+// with the settings of the classifier. If a species cannot be removed (see
+// RemoveData()), the species before it stay removed.
+func (bc *BayesClassifier) Unlearn(d *RawData) error {
+	if d.config != bc.config {
+		return ErrConfigMismatch
 	}
-	defer file.Close()
-	bc.WriteTo(file)
+	defer bc.prepare()
+	for _, spe := range d.species {
+		if err := bc.RemoveData(spe); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (bc *BayesClassifier) WriteTo(file io.Writer) {
-	enc := gob.NewEncoder(file)
-	err := enc.Encode(&FormatBayesClassifier{bc.Classes, bc.data, 
-		bc.globalData, bc.learned, bc.seen})
+// RemoveData() subtracts the words of a single species from the data of its
+// class and from the global data. A class left without species is removed.
+// A species whose class or words the classifier does not know is reported 
+// as ErrUnknownSpecies, and the classifier is left unchanged.
+func (bc *BayesClassifier) RemoveData(species *Species) error {
+	tempData, isExist := bc.data[species.Class]
+	if !isExist {
+		return fmt.Errorf("%w: %s is of the unknown class %s", 
+			ErrUnknownSpecies, species.Id, species.Class)
+	}
+	f := species.Features()
+	for i, word := range f.Words {
+		if tempData.Freq[word] == 0 || (tempData.Counts != nil && 
+			tempData.Counts[word] < int(f.Count(i))) {
+			return fmt.Errorf("%w: %s has words its class %s never had", 
+				ErrUnknownSpecies, species.Id, species.Class)
+		}
+	}
+	for i, word := range f.Words {
+		tempData.Freq[word]--
+		if tempData.Freq[word] == 0 {
+			delete(tempData.Freq, word)
+		}
+		if tempData.Counts != nil {
+			tempData.Counts[word] -= int(f.Count(i))
+			tempData.Total -= int(f.Count(i))
+			if tempData.Counts[word] == 0 {
+				delete(tempData.Counts, word)
+			}
+		}
+		bc.globalData.Add(word, -1)
+	}
+	tempData.Sum--
+	bc.learned--
+	if tempData.Sum == 0 {
+		delete(bc.data, species.Class)
+		delete(bc.lineages, species.Class)
+		for i, class := range bc.Classes {
+			if class == species.Class {
+				bc.Classes = append(bc.Classes[:i], bc.Classes[i+1:]...)
+				break
+			}
+		}
+	}
+	bc.absent = nil
+	return nil
+}
+
+// Merge() adds the counts of another classifier to this one, so that it 
+// predicts as if it had learned the species of both, these first. Both 
+// must use the same k-mer settings (or ErrConfigMismatch is returned) and 
+// the same model (or ErrInvalidModel is returned). The other classifier is
+// left unchanged.
+func (bc *BayesClassifier) Merge(other *BayesClassifier) error {
+	if other.config != bc.config {
+		return ErrConfigMismatch
+	}
+	if other.options != bc.options {
+		return fmt.Errorf("%w: cannot merge a %s model into a %s model", 
+			ErrInvalidModel, describeModel(other.options), 
+			describeModel(bc.options))
+	}
+	for _, class := range other.Classes {
+		otherData := other.data[class]
+		tempData, isExist := bc.data[class]
+		if !isExist {
+			tempData = newBayesClassData()
+			bc.data[class] = tempData
+			bc.Classes = append(bc.Classes, class)
+			if bc.lineages == nil {
+				bc.lineages = make(map[Class]Lineage)
+			}
+			bc.lineages[class] = other.lineage(class)
+		}
+		for word, freq := range otherData.Freq {
+			tempData.Freq[word] += freq
+		}
+		tempData.Sum += otherData.Sum
+		if tempData.Counts == nil || otherData.Counts == nil {
+			// Counts are only kept if both classifiers have them.
+			tempData.Counts, tempData.Total = nil, 0
+			continue
+		}
+		for word, count := range otherData.Counts {
+			tempData.Counts[word] += count
+		}
+		tempData.Total += otherData.Total
+	}
+	bc.globalData.Merge(other.globalData)
+	bc.learned += other.learned
+	bc.seen += other.seen
+	bc.prepare()
+	return nil
+}
+
+// MergeBayesClassifiers() loads several saved classifiers and merges them, 
+// in order, into a new one (see Merge()).
+func MergeBayesClassifiers(fileNames ...string) (*BayesClassifier, error) {
+	if len(fileNames) == 0 {
+		return nil, ErrUntrainedModel
+	}
+	merged, err := LoadBCFromFile(fileNames[0])
 	if err != nil {
-		fmt.Println("Error2: There is a problem when writing Bayes " + 
-					"classifier to a file!")
-		os.Exit(1)
+		return nil, err
 	}
-	fmt.Println("Write file successfully!")
+	for _, fileName := range fileNames[1:] {
+		other, err := LoadBCFromFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		if err := merged.Merge(other); err != nil {
+			return nil, fmt.Errorf("%s: %w", fileName, err)
+		}
+	}
+	return merged, nil
+}
+
+func describeModel(opts BayesOptions) string {
+	return fmt.Sprintf("%s (alpha %g, priors %t)", opts.Model, opts.Alpha, 
+		opts.Priors)
+}
+
+//Store a Bayes classifier to a .gob file.
+func (bc *BayesClassifier) BCWriteToFile(fileName string) error {
+	return createFile(fileName, func(file io.Writer) error {
+		_, err := bc.WriteTo(file)
+		return err
+	})
+}
+
+// WriteTo() gob-encodes the classifier.
+func (bc *BayesClassifier) WriteTo(file io.Writer) (int64, error) {
+	cw := &countingWriter{w: file}
+	enc := gob.NewEncoder(cw)
+	err := enc.Encode(&FormatBayesClassifier{bc.Classes, bc.data, 
+		bc.globalData, bc.lineages, bc.config, bc.learned, 
+		int(atomic.LoadInt64(&bc.seen)), bc.options})
+	return cw.n, err
 }
 
 //Predict the class of sequence, based on existing Bayes classifier.
-func (bc *BayesClassifier) BayesPredict(words []string) Class {
+func (bc *BayesClassifier) BayesPredict(words []Word) (Class, error) {
 	//bc := LoadBCFromFile()
+	if bc.learned == 0 {
+		return "", ErrUntrainedModel
+	}
+	predictClass := maxScore(bc.scores(Features{Words: words}))
+
+	atomic.AddInt64(&bc.seen, 1)
+	return predictClass, nil
+}
+
+// BootstrapOptions control the bootstrap confidence of naive Bayes 
+// predictions. Predictions with a confidence below Threshold are reported as
+// Unclassified.
+type BootstrapOptions struct {
+	Times		int
+	Threshold	float64
+	Seed		int64
+}
+
+var DefaultBootstrapOptions = BootstrapOptions{100, 0.8, 1}
+
+// SetBootstrap() sets the number of bootstrap replicates and the seed used
+// by Predict(). The threshold is left to the caller.
+func (bc *BayesClassifier) SetBootstrap(opts BootstrapOptions) {
+	bc.bootstrap = opts
+}
+
+// Name() returns "NBC", the name of the backend.
+func (bc *BayesClassifier) Name() string {
+	return "NBC"
+}
+
+// SetModel() sets the model the classes are scored with, and its 
+// hyperparameters. They are stored with the classifier. The multinomial 
+// model is reported as ErrInvalidModel for classifiers stored before word
+// counts were kept.
+func (bc *BayesClassifier) SetModel(opts BayesOptions) error {
+	if err := opts.Check(); err != nil {
+		return err
+	}
+	if opts.Model == MultinomialBayes {
+		for class, tempData := range bc.data {
+			if tempData.Counts == nil {
+				return fmt.Errorf("%w: class %s was learned without word "+
+					"counts", ErrInvalidModel, class)
+			}
+		}
+	}
+	bc.options = opts
+	bc.prepare()
+	return nil
+}
+
+// Model() returns the model the classes are scored with.
+func (bc *BayesClassifier) Model() BayesOptions {
+	return bc.options
+}
+
+// Predict() predicts a sequence with the bootstrap set by SetBootstrap().
+// The replicates are drawn from the seed and the words of the sequence, so
+// a sequence gets the same confidence wherever it is in a file.
+func (bc *BayesClassifier) Predict(f Features) (Prediction, error) {
+	return bc.predictConfidence(f, bc.bootstrap.Times, bc.bootstrapRand(f))
+}
+
+// bootstrapRand() returns the random source of the bootstrap of a sequence,
+// seeded with the bootstrap seed and a hash of the words of the sequence.
+func (bc *BayesClassifier) bootstrapRand(f Features) *rand.Rand {
+	h := fnv.New64a()
+	var b [8]byte
+	for _, word := range f.Words {
+		binary.LittleEndian.PutUint64(b[:], uint64(word))
+		h.Write(b[:])
+	}
+	return rand.New(rand.NewSource(bc.bootstrap.Seed ^ int64(h.Sum64())))
+}
+
+// BayesPredictConfidence() predicts the class and lineage of a sequence 
+// together with their bootstrap confidence, and the posterior probability of
+// every class (see Posteriors()). For the confidence (Wang et al., 2007), in
+// each of numOfBootstrap replicates, 1/k of the words of the sequence (one 
+// word per k bases) are drawn with replacement and classified again, and 
+// the confidence at each rank is the fraction of replicates whose lineage 
+// agrees with the prediction from all words at that rank.
+func (bc *BayesClassifier) BayesPredictConfidence(words []Word, 
+	numOfBootstrap int, r *rand.Rand) (Prediction, error) {
+	return bc.predictConfidence(Features{Words: words}, numOfBootstrap, r)
+}
+
+func (bc *BayesClassifier) predictConfidence(f Features, numOfBootstrap int,
+	r *rand.Rand) (Prediction, error) {
+	if bc.learned == 0 {
+		return Prediction{}, ErrUntrainedModel
+	}
+	if bc.options.Model == LegacyBayes {
+		// The legacy model counts every word of a sequence once.
+		f.Counts = nil
+	}
+	words := f.Words
+	scores := bc.scores(f)
+	// The most probable class comes first, ties broken by class name.
+	p := Prediction{Posteriors: posteriors(scores)}
+	if len(p.Posteriors) > 0 {
+		p.Class = p.Posteriors[0].Class
+	}
+	atomic.AddInt64(&bc.seen, 1)
+	p.Score = scores[p.Class]
+	p.Lineage = bc.lineage(p.Class)
+	if len(words) == 0 || numOfBootstrap <= 0 {
+		return p, nil
+	}
+	sampleSize := len(words) / bc.config.K
+	if sampleSize == 0 {
+		sampleSize = 1
+	}
+	sample := make([]Word, sampleSize)
+	var agree [NumRanks]int
+	for i := 0; i < numOfBootstrap; i++ {
+		for j := range sample {
+			sample[j] = words[r.Intn(len(words))]
+		}
+		// score() ignores the counts under the rdp and bernoulli models, so
+		// a word drawn more than once counts once there, while Wang et al.
+		// score a word as many times as it is drawn.
+		lineage := bc.lineage(maxScore(bc.scores(countWords(sample))))
+		for rank := 0; rank < NumRanks; rank++ {
+			if lineage[rank] == p.Lineage[rank] {
+				agree[rank]++
+			}
+		}
+	}
+	for rank := 0; rank < NumRanks; rank++ {
+		p.RankConfidence[rank] = float64(agree[rank]) / 
+			float64(numOfBootstrap)
+	}
+	p.Confidence = p.RankConfidence[RankGenus]
+	return p, nil
+}
+
+// A Posterior is the posterior probability of a class given a sequence.
+type Posterior struct {
+	Class			Class		`json:"class"`
+	Probability		float64		`json:"probability"`
+}
+
+// Posteriors() returns the posterior probability of the n most probable 
+// classes for a sequence, or of every class if n is not positive, the most
+// probable first. The log scores of the classes are normalized with the 
+// log-sum-exp trick, so that the probabilities of all classes add up to 1 
+// even when the scores themselves would underflow. Ties are sorted by class.
+func (bc *BayesClassifier) Posteriors(f Features, n int) ([]Posterior, 
+	error) {
+	if bc.learned == 0 {
+		return nil, ErrUntrainedModel
+	}
+	if bc.options.Model == LegacyBayes {
+		f.Counts = nil
+	}
+	ps := posteriors(bc.scores(f))
+	if n > 0 && n < len(ps) {
+		ps = ps[:n]
+	}
+	return ps, nil
+}
+
+// posteriors() normalizes log scores into probabilities, sorted as by 
+// Posteriors().
+func posteriors(scores map[Class]float64) []Posterior {
+	logs := make([]float64, 0, len(scores))
+	for _, score := range scores {
+		logs = append(logs, score)
+	}
+	total := logSumExp(logs)
+	ps := make([]Posterior, 0, len(scores))
+	for class, score := range scores {
+		ps = append(ps, Posterior{class, math.Exp(score - total)})
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Probability != ps[j].Probability {
+			return ps[i].Probability > ps[j].Probability
+		}
+		return ps[i].Class < ps[j].Class
+	})
+	return ps
+}
+
+// logSumExp() returns log(sum(exp(x))) of values, computed from their 
+// maximum so that it neither underflows nor overflows.
+func logSumExp(values []float64) float64 {
+	if len(values) == 0 {
+		return math.Inf(-1)
+	}
+	max := values[0]
+	for _, v := range values[1:] {
+		if v > max {
+			max = v
+		}
+	}
+	if math.IsInf(max, 0) {
+		return max
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
+
+// Return the lineage of a class. Classifiers stored before lineages were 
+// learned only know the genus.
+func (bc *BayesClassifier) lineage(class Class) Lineage {
+	lineage, isExist := bc.lineages[class]
+	if !isExist {
+		lineage[RankGenus] = class
+	}
+	return lineage
+}
+
+// Get scores of a sequence for every class, see score().
+func (bc *BayesClassifier) scores(f Features) map[Class]float64 {
 	length := len(bc.Classes)
 	scores := make(map[Class]float64, length)
 	for _, class := range bc.Classes {
-		scores[class] = bc.getWordsProb(class, words)
+		scores[class] = bc.score(class, f)
 	}
-	predictClass := maxScore(scores)
-
-	bc.seen++
-	return predictClass
+	return scores
 }
 
-// Load existing Bayes classifier from file.
-func LoadBCFromFile() *BayesClassifier {
-	file, err := os.Open("BayesClassifier.gob")
+// Load existing Bayes classifier from file. A missing file is reported as
+// ErrModelNotFound.
+func LoadBCFromFile(fileName string) (*BayesClassifier, error) {
+	file, err := openModel(fileName)
 	if err != nil {
-		fmt.Println("Error: There is a problem when loading Bayes classifier "+
-					"from file!")
-		os.Exit(1)
+		return nil, err
 	}
-	dec := gob.NewDecoder(file)
-	bc := new(FormatBayesClassifier)
-	err = dec.Decode(bc)
+	defer file.Close()
+	return ReadBayesClassifier(file)
+}
+
+// ReadBayesClassifier() decodes a classifier written by WriteTo(). A 
+// classifier that has not learned any species is reported as 
+// ErrUntrainedModel.
+func ReadBayesClassifier(file io.Reader) (*BayesClassifier, error) {
+	bc := new(BayesClassifier)
+	bc.SetBootstrap(DefaultBootstrapOptions)
+	if _, err := bc.ReadFrom(file); err != nil {
+		return nil, err
+	}
+	return bc, nil
+}
+
+// ReadFrom() replaces the classifier by one written by WriteTo(). The 
+// bootstrap settings are kept.
+func (bc *BayesClassifier) ReadFrom(file io.Reader) (int64, error) {
+	cr := &countingReader{r: file}
+	dec := gob.NewDecoder(cr)
+	fbc := new(FormatBayesClassifier)
+	err := dec.Decode(fbc)
 	if err != nil {
-		fmt.Println("Error: There is a problem when loading Bayes classifier "+
-					"from file!")
-		os.Exit(1)
+		return cr.n, err
+	}
+	if fbc.Learned == 0 {
+		return cr.n, ErrUntrainedModel
+	}
+	if err := fbc.Config.Check(); err != nil {
+		return cr.n, err
 	}
-	if bc.Learned == 0 {
-		fmt.Println("Error: Bayes classifier not initialized!")
-		os.Exit(1)
+	if err := fbc.Options.Check(); err != nil {
+		return cr.n, err
 	}
-	return &BayesClassifier{bc.Classes, bc.Data, bc.GlobalData, 
-		bc.Learned, bc.Seen}
+	bc.Classes, bc.data, bc.globalData = fbc.Classes, fbc.Data, 
+		fbc.GlobalData
+	bc.lineages, bc.config = fbc.Lineages, fbc.Config
+	bc.learned, bc.seen = fbc.Learned, int64(fbc.Seen)
+	bc.options = fbc.Options
+	bc.prepare()
+	return cr.n, nil
 }
 
-// Get score of a sequence, based on a specific class.
-func (bc *BayesClassifier) getWordsProb(class Class, words []string) float64 {
+// Config() returns the k-mer settings the classifier was trained with.
+func (bc *BayesClassifier) Config() KmerConfig {
+	return bc.config
+}
 
-	score := 0.0
-	for _, word := range words {
-		score += math.Log(bc.wordProb(class, word))
-	}
-	return score
+// Learned() returns the number of species the classifier has learned.
+func (bc *BayesClassifier) Learned() int {
+	return bc.learned
 }
 
 // Return the probability of a word existing in a sequence, based on a 
-//sepcific class.
-func (bc *BayesClassifier) wordProb(class Class, word string) float64 {
+//sepcific class, for the legacy model.
+func (bc *BayesClassifier) wordProb(class Class, word Word) float64 {
 	tempData := bc.data[class]
 	defaultProb := 1e-20
 	priorProb := bc.wordPriorProb(word) / (float64(tempData.Sum) + 1.0)
@@ -176,40 +633,32 @@ func (bc *BayesClassifier) wordProb(class Class, word string) float64 {
 	return (float64(wordFreq) / float64(tempData.Sum) + 1.0) + priorProb
 }
 
-func (bc *BayesClassifier) wordPriorProb(word string) float64{
-	sumOfWord := bc.globalData[word]
+func (bc *BayesClassifier) wordPriorProb(word Word) float64{
+	sumOfWord := bc.globalData.Get(word)
 	return (float64(sumOfWord))/(float64(bc.learned))
 }
 
-// Find the class having the maximum score.
+// Find the class having the maximum score. Ties go to the smallest class, 
+// so that the result does not depend on the order of the map.
 func maxScore(scores map[Class]float64) Class {
 	var maxClass Class
 	//fmt.Println(scores)
 	var maxscore float64
 	isInitial := true
 	for class, score := range scores {
-		if isInitial {
+		if isInitial || maxscore < score || 
+			(maxscore == score && class < maxClass) {
 			maxClass = class
 			maxscore = score
 			isInitial = false
 		}
-		if maxscore < score {
-			maxClass = class
-			maxscore = score
-		}
 	}
 	return maxClass
 }
 
 //Reset the Bayes classifier file.
-func ResetBayesClassifier() {
-	file, err := os.Create("BayesClassifier.gob")
-	if err != nil {
-		fmt.Println("Error1: There is a problem when resetting Bayes " +
-					"classifier file!")
-		os.Exit(1)
-	}
-	defer file.Close()
+func ResetBayesClassifier(fileName string) error {
+	return createFile(fileName, func(io.Writer) error { return nil })
 }
 
 
@@ -0,0 +1,395 @@
+package classifier
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+var testModels = []BayesModel{RDPBayes, BernoulliBayes, MultinomialBayes,
+	LegacyBayes}
+
+
+// learnModel() returns a classifier of a model that learned d.
+func learnModel(t *testing.T, model BayesModel, d *RawData) *BayesClassifier {
+	t.Helper()
+	bc, err := NewBayesClassifier(d.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultBayesOptions
+	opts.Model = model
+	if err := bc.SetModel(opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.Learn(d); err != nil {
+		t.Fatal(err)
+	}
+	return bc
+}
+
+// halves() splits a data set into its even and odd species.
+func halves(d *RawData) (*RawData, *RawData) {
+	even, odd := make([]int, 0), make([]int, 0)
+	for i := range d.Species() {
+		if i%2 == 0 {
+			even = append(even, i)
+		} else {
+			odd = append(odd, i)
+		}
+	}
+	return d.subset(even), d.subset(odd)
+}
+
+// samePosteriors() reports an error if two classifiers give different
+// posteriors for the queries, up to rounding.
+func samePosteriors(t *testing.T, got, want *BayesClassifier,
+	queries []Species) {
+	t.Helper()
+	for _, q := range queries {
+		gotPs, err := got.Posteriors(q.Features(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantPs, err := want.Posteriors(q.Features(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(gotPs) != len(wantPs) {
+			t.Fatalf("%s: %d classes, want %d", q.Id, len(gotPs),
+				len(wantPs))
+		}
+		wantByClass := make(map[Class]float64, len(wantPs))
+		for _, p := range wantPs {
+			wantByClass[p.Class] = p.Probability
+		}
+		for _, p := range gotPs {
+			want, isExist := wantByClass[p.Class]
+			if !isExist || math.Abs(p.Probability-want) > 1e-9 {
+				t.Errorf("%s: P(%s) = %g, want %g", q.Id, p.Class,
+					p.Probability, want)
+			}
+		}
+	}
+}
+
+func TestBayesPredictConfidence(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	bc, err := BayesLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range queries {
+		p, err := bc.BayesPredictConfidence(q.Words, 100,
+			rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		class, err := bc.BayesPredict(q.Words)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Class != class {
+			t.Errorf("%s: predicted %s with the bootstrap, %s without",
+				q.Id, p.Class, class)
+		}
+		if p.Lineage != bc.lineage(p.Class) {
+			t.Errorf("%s: lineage %v, want that of %s", q.Id, p.Lineage,
+				p.Class)
+		}
+		if p.Confidence < 0 || p.Confidence > 1 ||
+			p.Confidence != p.RankConfidence[RankGenus] {
+			t.Errorf("%s: confidence %g", q.Id, p.Confidence)
+		}
+		// All the genera are of the same family.
+		for r := RankDomain; r < RankGenus; r++ {
+			if p.RankConfidence[r] != 1 {
+				t.Errorf("%s: %s confidence %g, want 1", q.Id, r,
+					p.RankConfidence[r])
+			}
+		}
+		again, _ := bc.BayesPredictConfidence(q.Words, 100,
+			rand.New(rand.NewSource(1)))
+		if !reflect.DeepEqual(again, p) {
+			t.Errorf("%s: the same seed gives %+v and %+v", q.Id, p, again)
+		}
+		none, _ := bc.BayesPredictConfidence(q.Words, 0, nil)
+		if none.Class != p.Class || none.Lineage != p.Lineage ||
+			none.Confidence != 0 {
+			t.Errorf("%s: without bootstrap, got %+v", q.Id, none)
+		}
+	}
+}
+
+func TestPredictionThreshold(t *testing.T) {
+	tests := []struct {
+		confidence	float64
+		threshold	float64
+		want		Class
+	}{
+		{0.5, 0.8, Unclassified},
+		{0.8, 0.8, "Bacillus"},
+		{1, 0.8, "Bacillus"},
+		{0, 0, "Bacillus"},
+	}
+	for _, test := range tests {
+		p := Prediction{Class: "Bacillus", Confidence: test.confidence}
+		if got := p.Threshold(test.threshold); got != test.want {
+			t.Errorf("confidence %g, threshold %g: got %s, want %s",
+				test.confidence, test.threshold, got, test.want)
+		}
+	}
+}
+
+
+func TestLogSumExp(t *testing.T) {
+	tests := []struct {
+		name	string
+		values	[]float64
+		want	float64
+	}{
+		{"empty", nil, math.Inf(-1)},
+		{"single", []float64{-3}, -3},
+		{"equal", []float64{0, 0}, math.Log(2)},
+		{"underflowing", []float64{-2000, -2000}, -2000 + math.Log(2)},
+		{"overflowing", []float64{1000, 1000 + math.Log(3)},
+			1000 + math.Log(4)},
+		{"negligible", []float64{0, -1000}, 0},
+		{"all impossible", []float64{math.Inf(-1), math.Inf(-1)},
+			math.Inf(-1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := logSumExp(test.values)
+			if got != test.want && math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("logSumExp(%v) = %g, want %g", test.values, got,
+					test.want)
+			}
+		})
+	}
+}
+
+func TestPosteriors(t *testing.T) {
+	ps := posteriors(map[Class]float64{"A": -1500, "B": -1501, "C": -1500})
+	sum := 0.0
+	for i, p := range ps {
+		sum += p.Probability
+		if i > 0 && p.Probability > ps[i-1].Probability {
+			t.Errorf("posteriors not sorted: %v", ps)
+		}
+	}
+	if math.Abs(sum-1) > 1e-12 {
+		t.Errorf("posteriors add up to %g, want 1", sum)
+	}
+	if ps[0].Class != "A" || ps[1].Class != "C" || ps[2].Class != "B" {
+		t.Errorf("posteriors = %v, want A and C (by class) before B", ps)
+	}
+}
+
+// Merging a classifier trained on half of the data into one trained on the
+
+func TestBayesPosteriorsTopN(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	bc, err := BayesLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := bc.Posteriors(queries[0].Features(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(d.Classes()) {
+		t.Errorf("got %d posteriors, want one per class", len(all))
+	}
+	top, err := bc.Posteriors(queries[0].Features(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(top, all[:3]) {
+		t.Errorf("top 3 = %v, want %v", top, all[:3])
+	}
+	class, err := bc.BayesPredict(queries[0].Words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top[0].Class != class {
+		t.Errorf("most probable class %s, predicted %s", top[0].Class,
+			class)
+	}
+	untrained, err := NewBayesClassifier(DefaultKmerConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := untrained.Posteriors(queries[0].Features(),
+		0); err != ErrUntrainedModel {
+		t.Errorf("got %v, want ErrUntrainedModel", err)
+	}
+}
+
+// Merging a classifier trained on half of the data into one trained on the
+// other half must give the classifier trained on all of it.
+func TestMergeEqualsLearningBoth(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	first, second := halves(d)
+	for _, model := range testModels {
+		t.Run(model.String(), func(t *testing.T) {
+			merged := learnModel(t, model, first)
+			if err := merged.Merge(learnModel(t, model, second)); err != nil {
+				t.Fatal(err)
+			}
+			if merged.Learned() != len(d.Species()) {
+				t.Errorf("learned %d species, want %d", merged.Learned(),
+					len(d.Species()))
+			}
+			samePosteriors(t, merged, learnModel(t, model, d), queries)
+		})
+	}
+}
+
+func TestMergeMismatch(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	bc := learnModel(t, LegacyBayes, d)
+	err := bc.Merge(learnModel(t, MultinomialBayes, d))
+	if !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("merging another model: got %v, want ErrInvalidModel", err)
+	}
+	cfg := DefaultKmerConfig
+	cfg.K--
+	other, _ := unbalancedData(cfg)
+	err = bc.Merge(learnModel(t, LegacyBayes, other))
+	if !errors.Is(err, ErrConfigMismatch) {
+		t.Errorf("merging another k: got %v, want ErrConfigMismatch", err)
+	}
+}
+
+// A classifier stored without lineages, which decodes with a nil map, must
+// learn the lineages of the species it learns next.
+func TestLearnWithoutLineages(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	first, second := halves(d)
+	bc := learnModel(t, MultinomialBayes, first)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&FormatBayesClassifier{
+		bc.Classes, bc.data, bc.globalData, nil, bc.config, bc.learned, 0,
+		bc.options}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadBayesClassifier(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Learn(second); err != nil {
+		t.Fatal(err)
+	}
+	for _, spe := range second.Species() {
+		if got := loaded.lineage(spe.Class); got != spe.Lineage {
+			t.Errorf("%s: lineage %v, want %v", spe.Class, got, spe.Lineage)
+		}
+	}
+}
+
+// Unlearning half of the data must give the classifier trained on the
+// other half.
+func TestUnlearnRoundTrip(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	kept, removed := halves(d)
+	for _, model := range testModels {
+		t.Run(model.String(), func(t *testing.T) {
+			bc := learnModel(t, model, d)
+			if err := bc.Unlearn(removed); err != nil {
+				t.Fatal(err)
+			}
+			if bc.Learned() != len(kept.Species()) {
+				t.Errorf("learned %d species, want %d", bc.Learned(),
+					len(kept.Species()))
+			}
+			samePosteriors(t, bc, learnModel(t, model, kept), queries)
+		})
+	}
+}
+
+func TestUnlearnUnknownSpecies(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	first, second := halves(d)
+	tests := []struct {
+		name	string
+		data	*RawData
+	}{
+		{"unknown class", NewRawData(&[]Species{newTestSpecies("x",
+			"Unknown", queries[0].Sequence, DefaultKmerConfig)},
+			DefaultKmerConfig)},
+		{"unknown words", NewRawData(&queries, DefaultKmerConfig)},
+		{"not learned", second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bc := learnModel(t, MultinomialBayes, first)
+			if err := bc.Unlearn(test.data); !errors.Is(err,
+				ErrUnknownSpecies) {
+				t.Errorf("got %v, want ErrUnknownSpecies", err)
+			}
+		})
+	}
+}
+
+func TestBayesClassifierSaveLoad(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	for _, model := range testModels {
+		t.Run(model.String(), func(t *testing.T) {
+			bc := learnModel(t, model, d)
+			var buf bytes.Buffer
+			if _, err := bc.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := ReadBayesClassifier(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Model() != bc.Model() ||
+				loaded.Config() != bc.Config() ||
+				loaded.Learned() != bc.Learned() {
+				t.Errorf("loaded %v %v %d, want %v %v %d", loaded.Model(),
+					loaded.Config(), loaded.Learned(), bc.Model(),
+					bc.Config(), bc.Learned())
+			}
+			samePosteriors(t, loaded, bc, queries)
+		})
+	}
+}
+
+// A sequence must get the same confidence wherever it is in a file, and
+// predictions may run concurrently.
+func TestBayesPredictOrder(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	bc, err := BayesLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forward := make([]Prediction, len(queries))
+	for i, q := range queries {
+		if forward[i], err = bc.Predict(q.Features()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backward := make([]Prediction, len(queries))
+	errs := make(chan error, len(queries))
+	for i := len(queries) - 1; i >= 0; i-- {
+		go func(i int) {
+			var err error
+			backward[i], err = bc.Predict(queries[i].Features())
+			errs <- err
+		}(i)
+	}
+	for range queries {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(forward, backward) {
+		t.Error("the predictions depend on the order of the queries")
+	}
+}
@@ -0,0 +1,180 @@
+package classifier
+
+import (
+	"fmt"
+	"math"
+)
+
+// BayesModel tells how the naive Bayes classifier scores the words of a
+// sequence for a class. It is stored with the classifier.
+type BayesModel int
+
+const (
+	// The scoring of the first versions of the classifier, which is not a
+	// probability. It is kept so that the predictions of those versions can
+	// be reproduced and compared with the other models.
+	LegacyBayes BayesModel = iota
+	// Every word of the vocabulary is present in or absent from a sequence,
+	// with the probability of the fraction of the species of the class
+	// containing it. As the absence of the many words a sequence does not
+	// have counts too, large classes are favoured over small ones.
+	BernoulliBayes
+	// The words of a sequence are drawn from the word counts of the class,
+	// so words occurring several times count several times.
+	MultinomialBayes
+	// Only the words of the sequence are scored, with the fraction of the
+	// species of the class containing them, smoothed by the fraction of all
+	// species containing them, as in the RDP classifier (Wang et al., 2007).
+	RDPBayes
+)
+
+var bayesModelNames = []string{"legacy", "bernoulli", "multinomial", "rdp"}
+
+func (m BayesModel) String() string {
+	return nameOf(bayesModelNames, int(m))
+}
+
+func (m *BayesModel) Set(name string) error {
+	i, err := parseName(bayesModelNames, name, "naive Bayes model")
+	if err == nil {
+		*m = BayesModel(i)
+	}
+	return err
+}
+
+// BayesOptions are the model of the naive Bayes classifier and its
+// hyperparameters. Alpha is the pseudocount added to every word count
+// (Lidstone smoothing; 1 is Laplace smoothing) by the Bernoulli and 
+// multinomial models, and Priors tells if the
+// score of a class includes its prior, the fraction of the learned species
+// belonging to it. Without priors every class is equally likely.
+type BayesOptions struct {
+	Model		BayesModel
+	Alpha		float64
+	Priors		bool
+}
+
+var DefaultBayesOptions = BayesOptions{RDPBayes, 1, false}
+
+// Check() returns an error wrapping ErrInvalidModel if the options are out
+// of range.
+func (o BayesOptions) Check() error {
+	if o.Model < LegacyBayes || o.Model > RDPBayes {
+		return fmt.Errorf("%w: unknown model %d", ErrInvalidModel, o.Model)
+	}
+	if (o.Model == BernoulliBayes || o.Model == MultinomialBayes) && 
+		!(o.Alpha > 0) {
+		return fmt.Errorf("%w: the pseudocount must be positive, not %g",
+			ErrInvalidModel, o.Alpha)
+	}
+	return nil
+}
+
+// vocabulary() returns the number of distinct words there may be. With
+// canonical k-mers a word and its reverse complement are one word, and only
+// words of even length may be their own reverse complement.
+func (c KmerConfig) vocabulary() float64 {
+	n := math.Exp2(float64(2 * c.K))
+	if !c.Canonical {
+		return n
+	}
+	if c.K%2 == 0 {
+		return (n + math.Exp2(float64(c.K))) / 2
+	}
+	return n / 2
+}
+
+// score() returns the log probability of the features of a sequence given a
+// class, plus the log prior of the class if priors are used.
+func (bc *BayesClassifier) score(class Class, f Features) float64 {
+	tempData := bc.data[class]
+	score := 0.0
+	switch bc.options.Model {
+	case LegacyBayes:
+		for i, word := range f.Words {
+			score += float64(f.Count(i)) * math.Log(bc.wordProb(class, word))
+		}
+	case BernoulliBayes:
+		// Every word is first taken as absent, and the words of the
+		// sequence are then switched to present.
+		score = bc.absentScore(class)
+		for _, word := range f.Words {
+			p := bc.presenceProb(tempData, tempData.Freq[word])
+			score += math.Log(p) - math.Log1p(-p)
+		}
+	case MultinomialBayes:
+		alpha := bc.options.Alpha
+		total := math.Log(float64(tempData.Total) +
+			alpha*bc.config.vocabulary())
+		for i, word := range f.Words {
+			count := float64(tempData.Counts[word])
+			score += float64(f.Count(i)) * (math.Log(count+alpha) - total)
+		}
+	case RDPBayes:
+		// P(w|class) = (m(w) + P(w)) / (M + 1), where m(w) of the M species
+		// of the class contain w, and P(w) = (n(w) + 0.5) / (N + 1) if n(w)
+		// of all N species do.
+		total := math.Log(float64(tempData.Sum) + 1)
+		for _, word := range f.Words {
+			prior := (float64(bc.globalData.Get(word)) + 0.5) / 
+				(float64(bc.learned) + 1)
+			score += math.Log(float64(tempData.Freq[word])+prior) - total
+		}
+	}
+	if bc.options.Priors {
+		score += math.Log(float64(tempData.Sum) / float64(bc.learned))
+	}
+	return score
+}
+
+// presenceProb() returns the smoothed probability that a species of a class
+// contains a word found in freq of its species.
+func (bc *BayesClassifier) presenceProb(tempData *BayesClassData,
+	freq int) float64 {
+	alpha := bc.options.Alpha
+	return (float64(freq) + alpha) / (float64(tempData.Sum) + 2*alpha)
+}
+
+// prepare() computes the absent score of every class for the Bernoulli 
+// model, once the classifier has changed, so that predictions only read 
+// the classifier.
+func (bc *BayesClassifier) prepare() {
+	bc.absent = nil
+	if bc.options.Model != BernoulliBayes {
+		return
+	}
+	absent := make(map[Class]float64, len(bc.data))
+	for class := range bc.data {
+		absent[class] = bc.computeAbsentScore(class)
+	}
+	bc.absent = absent
+}
+
+// absentScore() returns the log probability of a class having none of the
+// words of the vocabulary. It only changes when the classifier learns, so
+// it is computed by prepare(), unless a species was learned or removed 
+// alone (with UpdateData() or RemoveData()) since.
+func (bc *BayesClassifier) absentScore(class Class) float64 {
+	if score, isExist := bc.absent[class]; isExist {
+		return score
+	}
+	return bc.computeAbsentScore(class)
+}
+
+func (bc *BayesClassifier) computeAbsentScore(class Class) float64 {
+	// The words are grouped by frequency, so that the sum is the same 
+	// whatever the order of the map.
+	tempData := bc.data[class]
+	numOfWords := make([]int, tempData.Sum+1)
+	for _, freq := range tempData.Freq {
+		numOfWords[freq]++
+	}
+	unseen := bc.config.vocabulary() - float64(len(tempData.Freq))
+	score := unseen * math.Log1p(-bc.presenceProb(tempData, 0))
+	for freq, n := range numOfWords {
+		if n > 0 {
+			score += float64(n) * math.Log1p(-bc.presenceProb(tempData, freq))
+		}
+	}
+	return score
+}
@@ -0,0 +1,50 @@
+package classifier
+
+import (
+	"testing"
+)
+
+// The models must predict the genus of the queries, whatever the size of
+// the genera, and the RDP model with confidence.
+func TestBayesModelsUnbalancedClasses(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	tests := []struct {
+		model			BayesModel
+		minConfidence	float64
+	}{
+		{RDPBayes, 0.8},
+		{MultinomialBayes, 0},
+		{LegacyBayes, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.model.String(), func(t *testing.T) {
+			bc := learnModel(t, test.model, d)
+			for _, q := range queries {
+				p, err := bc.Predict(q.Features())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if p.Class != q.Class {
+					t.Errorf("%s: predicted %s, want %s", q.Id, p.Class,
+						q.Class)
+				} else if p.Confidence < test.minConfidence {
+					t.Errorf("%s: confidence %.2f, want at least %.2f",
+						q.Id, p.Confidence, test.minConfidence)
+				}
+			}
+		})
+	}
+}
+
+func TestBayesModelFlag(t *testing.T) {
+	for _, name := range bayesModelNames {
+		var m BayesModel
+		if err := m.Set(name); err != nil || m.String() != name {
+			t.Errorf("Set(%q) gives %s, %v", name, m, err)
+		}
+	}
+	var m BayesModel
+	if err := m.Set("gaussian"); err == nil {
+		t.Error("Set(\"gaussian\") did not fail")
+	}
+}
@@ -0,0 +1,141 @@
+package classifier
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A Classifier learns species from their words and predicts the lineage of
+// new sequences. Settings that only matter for predictions, such as the
+// number of neighbours of the kNN classifier, are set on the backend itself.
+type Classifier interface {
+	// Name() returns the name the backend is registered under.
+	Name() string
+	// Config() returns the k-mer settings the classifier was trained with.
+	Config() KmerConfig
+	// Learn() adds the species of a data set to the classifier.
+	Learn(d *RawData) error
+	// Predict() predicts the lineage of a sequence from its words and 
+	// their counts. The Score of the prediction is only compared between 
+	// predictions of the same classifier.
+	Predict(f Features) (Prediction, error)
+	// WriteTo() saves the classifier, and ReadFrom() replaces it by a saved
+	// one.
+	WriteTo(w io.Writer) (int64, error)
+	ReadFrom(r io.Reader) (int64, error)
+}
+
+// A Backend creates empty classifiers of one kind.
+type Backend struct {
+	New			func(cfg KmerConfig) (Classifier, error)
+	// The file the command line tool stores the classifier in.
+	Model		string
+}
+
+var backends = make(map[string]Backend)
+
+// Register() makes a backend available by name. It is called from the init()
+// function of the file defining the backend.
+func Register(name string, b Backend) {
+	if _, isExist := backends[name]; isExist {
+		panic("classifier: backend " + name + " registered twice")
+	}
+	backends[name] = b
+}
+
+// Backends() returns the names of the registered backends, sorted.
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (Backend, error) {
+	b, isExist := backends[name]
+	if !isExist {
+		return b, fmt.Errorf("%w: %q (known backends: %v)",
+			ErrUnknownBackend, name, Backends())
+	}
+	return b, nil
+}
+
+// New() returns an empty classifier of the named backend.
+func New(name string, cfg KmerConfig) (Classifier, error) {
+	b, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.New(cfg)
+}
+
+// ModelFile() returns the file the command line tool stores a classifier of
+// the named backend in.
+func ModelFile(name string) (string, error) {
+	b, err := lookup(name)
+	return b.Model, err
+}
+
+// Load() reads a saved classifier of the named backend. An empty file name
+// stands for the default file of the backend.
+func Load(name, fileName string) (Classifier, error) {
+	b, err := lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if fileName == "" {
+		fileName = b.Model
+	}
+	c, err := b.New(DefaultKmerConfig)
+	if err != nil {
+		return nil, err
+	}
+	file, err := openModel(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := c.ReadFrom(file); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save() writes a classifier to a file.
+func Save(c Classifier, fileName string) error {
+	return createFile(fileName, func(file io.Writer) error {
+		_, err := c.WriteTo(file)
+		return err
+	})
+}
+
+// An Ensemble predicts every sequence with several classifiers. The
+// classifiers may use different k-mer settings.
+type Ensemble []Classifier
+
+// Predict() returns the prediction of every classifier of the ensemble for
+// a sequence, in order. See PredictStrands() for bothStrands.
+func (e Ensemble) Predict(sequence string,
+	bothStrands bool) ([]Prediction, error) {
+	predictions := make([]Prediction, len(e))
+	for i, c := range e {
+		p, err := PredictStrands(sequence, c, bothStrands)
+		if err != nil {
+			return nil, err
+		}
+		predictions[i] = p
+	}
+	return predictions, nil
+}
+
+// Names() returns the names of the classifiers of the ensemble.
+func (e Ensemble) Names() []string {
+	names := make([]string, len(e))
+	for i, c := range e {
+		names[i] = c.Name()
+	}
+	return names
+}
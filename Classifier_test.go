@@ -0,0 +1,51 @@
+package classifier
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// Every backend must predict the same after being saved and loaded.
+func TestBackendsSaveLoad(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	for _, name := range Backends() {
+		t.Run(name, func(t *testing.T) {
+			c, err := New(name, DefaultKmerConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Learn(d); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if _, err := c.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := New(name, DefaultKmerConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loaded.ReadFrom(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Name() != c.Name() || loaded.Config() != c.Config() {
+				t.Errorf("loaded %s %v, want %s %v", loaded.Name(),
+					loaded.Config(), c.Name(), c.Config())
+			}
+			for _, q := range queries {
+				want, err := c.Predict(q.Features())
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := loaded.Predict(q.Features())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: predicted %+v, want %+v", q.Id, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package classifier
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// ClassifyFile() streams every read of a FASTA/FASTQ file through the 
+// classifiers of an ensemble, which are loaded only once, and writes one 
+// tab-separated row (read ID, predicted class, classifier, confidence, 
+// strand, lineage) per read and classifier. Predictions below the confidence
+// threshold are written as unclassified, and the lineage is truncated at the
+// deepest rank reaching the threshold. If bothStrands is set, reads are also
+// classified as their reverse complement (see PredictStrands()). It returns
+// the number of reads classified, and the first error met; the rows of the
+// reads classified before it are written all the same.
+func ClassifyFile(file io.Reader, outfile io.Writer, e Ensemble, 
+	threshold float64, bothStrands bool) (count int, err error) {
+	reader := NewSequenceReader(file)
+	writer := bufio.NewWriter(outfile)
+	defer func() {
+		if flushErr := writer.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	names := e.Names()
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		predictions, err := e.Predict(s.Sequence, bothStrands)
+		if err != nil {
+			return count, err
+		}
+		for i, p := range predictions {
+			writePrediction(writer, s.Id, p, names[i], threshold)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// PredictStrands() predicts a sequence with a classifier. If bothStrands is
+// set, its reverse complement is predicted too, and the prediction with the
+// higher score is kept together with its strand. Classifiers using 
+// canonical k-mers see both strands at once, so they predict the sequence 
+// only once.
+func PredictStrands(sequence string, c Classifier, 
+	bothStrands bool) (Prediction, error) {
+	cfg := c.Config()
+	p, err := c.Predict(GenerateFeatures(sequence, cfg))
+	if err != nil {
+		return p, err
+	}
+	if cfg.Canonical {
+		p.Strand = BothStrands
+		return p, nil
+	}
+	if !bothStrands {
+		return p, nil
+	}
+	reverse, err := c.Predict(GenerateFeatures(ReverseComplement(sequence),
+		cfg))
+	if err != nil {
+		return p, err
+	}
+	if reverse.Score > p.Score {
+		reverse.Strand = ReverseStrand
+		return reverse, nil
+	}
+	return p, nil
+}
+
+func writePrediction(writer io.Writer, id string, p Prediction, 
+	classifier string, threshold float64) {
+	fmt.Fprintf(writer, "%s\t%s\t%s\t%.2f\t%s\t%s\n", id, 
+		p.Threshold(threshold), classifier, p.Confidence, p.Strand, 
+		p.FormatLineage(threshold))
+}
@@ -0,0 +1,116 @@
+package classifier
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// randomSequence() returns a random sequence of n bases.
+func randomSequence(r *rand.Rand, n int) string {
+	bases := make([]byte, n)
+	for i := range bases {
+		bases[i] = "ACGT"[r.Intn(4)]
+	}
+	return string(bases)
+}
+
+// row() returns the row of a read predicted with full confidence.
+func row(id, genus, classifier, strand string) string {
+	return id + "\t" + genus + "\t" + classifier + "\t1.00\t" + strand +
+		"\tBacteria(1.00);Firmicutes(1.00);Bacilli(1.00);Bacillales(1.00);" +
+		"Bacillaceae(1.00);" + genus + "(1.00)\n"
+}
+
+func TestClassifyFile(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sequences := []string{randomSequence(r, 300), randomSequence(r, 300)}
+	species := []Species{
+		newTestSpecies("a", "GenusA", sequences[0], DefaultKmerConfig),
+		newTestSpecies("b", "GenusB", sequences[1], DefaultKmerConfig),
+	}
+	d := NewRawData(&species, DefaultKmerConfig)
+	bc, err := BayesLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc, err := KNNLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kc.SetK(1); err != nil {
+		t.Fatal(err)
+	}
+	file := ">read1 sample=1\n" + sequences[0][:100] + "\n" +
+		sequences[0][100:200] + "\n>read2\n" + sequences[1][50:250] + "\n"
+	reverse := ">read1\n" + ReverseComplement(sequences[0][:200]) +
+		"\n>read2\n" + sequences[1][50:250] + "\n"
+	tests := []struct {
+		name		string
+		e			Ensemble
+		file		string
+		bothStrands	bool
+		want		string
+	}{
+		{"NBC", Ensemble{bc}, file, false, row("read1", "GenusA", "NBC", "+") +
+			row("read2", "GenusB", "NBC", "+")},
+		{"KNN", Ensemble{kc}, file, false, row("read1", "GenusA", "KNN", "+") +
+			row("read2", "GenusB", "KNN", "+")},
+		{"NBKNN", Ensemble{bc, kc}, file, false,
+			row("read1", "GenusA", "NBC", "+") +
+			row("read1", "GenusA", "KNN", "+") +
+			row("read2", "GenusB", "NBC", "+") +
+			row("read2", "GenusB", "KNN", "+")},
+		{"both strands", Ensemble{bc, kc}, reverse, true,
+			row("read1", "GenusA", "NBC", "-") +
+			row("read1", "GenusA", "KNN", "-") +
+			row("read2", "GenusB", "NBC", "+") +
+			row("read2", "GenusB", "KNN", "+")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			n, err := ClassifyFile(strings.NewReader(test.file), &out,
+				test.e, DefaultBootstrapOptions.Threshold, test.bothStrands)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 2 {
+				t.Errorf("classified %d reads, want 2", n)
+			}
+			if out.String() != test.want {
+				t.Errorf("got\n%s\nwant\n%s", out.String(), test.want)
+			}
+		})
+	}
+}
+
+// The rows of the reads classified before a malformed record must be 
+// written.
+func TestClassifyFileMalformed(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sequence := randomSequence(r, 300)
+	species := []Species{
+		newTestSpecies("a", "GenusA", sequence, DefaultKmerConfig),
+	}
+	bc, err := BayesLearnData(NewRawData(&species, DefaultKmerConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := "@read1\n" + sequence[:100] + "\n+\n" + 
+		strings.Repeat("I", 100) + "\n@read2\n" + sequence[:100] + 
+		"\n+\n" + strings.Repeat("I", 50) + "\n"
+	var out bytes.Buffer
+	n, err := ClassifyFile(strings.NewReader(file), &out, Ensemble{bc},
+		DefaultBootstrapOptions.Threshold, false)
+	if !errors.Is(err, ErrMalformedRecord) {
+		t.Errorf("got %v, want ErrMalformedRecord", err)
+	}
+	if want := row("read1", "GenusA", "NBC", "+"); n != 1 || 
+		out.String() != want {
+		t.Errorf("classified %d reads into\n%s\nwant 1 into\n%s", n,
+			out.String(), want)
+	}
+}
@@ -0,0 +1,310 @@
+package classifier
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// CVMode tells how the species of a data set are split into folds.
+type CVMode int
+
+const (
+	// Split the shuffled species into folds of (nearly) equal size.
+	KFold CVMode = iota
+	// Split the species of every class evenly across the folds, so that
+	// each fold has about the class proportions of the whole data set.
+	StratifiedKFold
+	// Make every species its own fold.
+	LeaveOneOut
+)
+
+var cvModeNames = []string{"kfold", "stratified", "loo"}
+
+func (m CVMode) String() string {
+	return nameOf(cvModeNames, int(m))
+}
+
+func (m *CVMode) Set(name string) error {
+	i, err := parseName(cvModeNames, name, "cross-validation mode")
+	if err == nil {
+		*m = CVMode(i)
+	}
+	return err
+}
+
+// CVOptions control a cross-validation. Folds is ignored for leave-one-out.
+// MinK and MaxK give the range of neighbours tried for classifiers having a
+// number of neighbours (see NeighbourSetter), Metric their similarity, 
+// Voting how their votes are weighed and Hierarchical whether the lineage 
+// is voted rank by rank. Bayes is the model of naive Bayes classifiers.
+type CVOptions struct {
+	Mode			CVMode
+	Folds			int
+	Seed			int64
+	MinK			int
+	MaxK			int
+	Metric			Similarity
+	Voting			VotingScheme
+	Hierarchical	bool
+	Bayes			BayesOptions
+}
+
+var DefaultCVOptions = CVOptions{StratifiedKFold, 10, 1, 1, 10, SharedWords,
+	UniformVote, false, DefaultBayesOptions}
+
+// A NeighbourSetter is a classifier whose predictions depend on a number of
+// neighbours, such as the kNN classifier.
+type NeighbourSetter interface {
+	SetK(k int) error
+}
+
+// A NeighbourPredictor is a lazy classifier that, having learned a whole 
+// data set, can leave species out at query time and predict for several 
+// numbers of neighbours at once (see KNNClassifier.PredictNeighbours()).
+type NeighbourPredictor interface {
+	PredictNeighbours(f Features, ks []int, 
+		exclude func(id int) bool) ([]Prediction, error)
+}
+
+// A CVResult holds the accuracy of every fold for one number of neighbours
+// (0 for classifiers without neighbours), and their mean and standard
+// deviation.
+type CVResult struct {
+	K			int
+	Accuracies	[]float64
+	Mean		float64
+	Std			float64
+}
+
+// CrossValidate() cross-validates the named backend on a data set. For
+// every fold a classifier is learned from the other folds, and the species
+// of the fold are predicted with every number of neighbours of the range.
+// A NeighbourPredictor learns the whole data set only once instead, and 
+// leaves the fold of each query out of its neighbours. The folds only 
+// depend on the data set and the seed, so a cross-validation can be 
+// repeated exactly, and both backends can be compared on the same folds.
+func CrossValidate(name string, d *RawData, opts CVOptions) ([]CVResult,
+	error) {
+	folds, err := makeFolds(d, opts)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newForCV(name, d, opts)
+	if err != nil {
+		return nil, err
+	}
+	ks := []int{0}
+	if _, isNeighbourSetter := c.(NeighbourSetter); isNeighbourSetter {
+		if opts.MinK < 1 || opts.MaxK < opts.MinK {
+			return nil, ErrInvalidK
+		}
+		ks = make([]int, 0, opts.MaxK-opts.MinK+1)
+		for k := opts.MinK; k <= opts.MaxK; k++ {
+			ks = append(ks, k)
+		}
+	}
+	results := make([]CVResult, len(ks))
+	for i, k := range ks {
+		results[i] = CVResult{K: k, Accuracies: make([]float64, len(folds))}
+	}
+	if _, isLazy := c.(NeighbourPredictor); isLazy {
+		err = crossValidateLazy(c, d, folds, ks, results)
+	} else {
+		err = crossValidateFolds(name, d, opts, folds, ks, results)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Mean, results[i].Std = meanStd(results[i].Accuracies)
+	}
+	return results, nil
+}
+
+// newForCV() returns an empty classifier of the named backend, set up for
+// a cross-validation.
+func newForCV(name string, d *RawData, opts CVOptions) (Classifier, error) {
+	c, err := New(name, d.config)
+	if err != nil {
+		return nil, err
+	}
+	switch c := c.(type) {
+	case *BayesClassifier:
+		// The bootstrap does not change the predicted class.
+		c.SetBootstrap(BootstrapOptions{})
+		if err := c.SetModel(opts.Bayes); err != nil {
+			return nil, err
+		}
+	case *KNNClassifier:
+		c.SetMetric(opts.Metric)
+		c.SetVoting(opts.Voting)
+		c.SetHierarchical(opts.Hierarchical)
+	}
+	return c, nil
+}
+
+// crossValidateFolds() learns a new classifier for every fold.
+func crossValidateFolds(name string, d *RawData, opts CVOptions, 
+	folds [][]int, ks []int, results []CVResult) error {
+	for f, test := range folds {
+		c, err := newForCV(name, d, opts)
+		if err != nil {
+			return err
+		}
+		if err := c.Learn(d.without(test)); err != nil {
+			return err
+		}
+		for i, k := range ks {
+			if k > 0 {
+				if err := c.(NeighbourSetter).SetK(k); err != nil {
+					return err
+				}
+			}
+			correct := 0
+			for _, id := range test {
+				p, err := c.Predict(d.species[id].Features())
+				if err != nil {
+					return err
+				}
+				if p.Class == d.species[id].Class {
+					correct++
+				}
+			}
+			results[i].Accuracies[f] = float64(correct) / float64(len(test))
+		}
+	}
+	return nil
+}
+
+// crossValidateLazy() learns the whole data set once, and predicts every 
+// species for all k at once, leaving out the species of its fold.
+func crossValidateLazy(c Classifier, d *RawData, folds [][]int, ks []int, 
+	results []CVResult) error {
+	if err := c.Learn(d); err != nil {
+		return err
+	}
+	foldOf := make([]int, len(d.species))
+	for f, test := range folds {
+		for _, id := range test {
+			foldOf[id] = f
+		}
+	}
+	np := c.(NeighbourPredictor)
+	for f, test := range folds {
+		exclude := func(id int) bool { return foldOf[id] == f }
+		correct := make([]int, len(ks))
+		for _, id := range test {
+			predictions, err := np.PredictNeighbours(
+				d.species[id].Features(), ks, exclude)
+			if err != nil {
+				return err
+			}
+			for i, p := range predictions {
+				if p.Class == d.species[id].Class {
+					correct[i]++
+				}
+			}
+		}
+		for i := range ks {
+			results[i].Accuracies[f] = float64(correct[i]) / 
+				float64(len(test))
+		}
+	}
+	return nil
+}
+
+// makeFolds() splits the ids of the species of a data set into folds.
+func makeFolds(d *RawData, opts CVOptions) ([][]int, error) {
+	n := len(d.species)
+	numOfFolds := opts.Folds
+	if opts.Mode == LeaveOneOut {
+		numOfFolds = n
+	}
+	if numOfFolds < 2 || numOfFolds > n {
+		return nil, fmt.Errorf("classifier: cannot split %d species into "+
+			"%d folds", n, numOfFolds)
+	}
+	r := rand.New(rand.NewSource(opts.Seed))
+	folds := make([][]int, numOfFolds)
+	switch opts.Mode {
+	case KFold, LeaveOneOut:
+		for i, id := range r.Perm(n) {
+			folds[i%numOfFolds] = append(folds[i%numOfFolds], id)
+		}
+	case StratifiedKFold:
+		// The species of each class are shuffled and dealt to the folds
+		// in turn, going on from where the previous class stopped.
+		byClass := make(map[Class][]int)
+		for id, spe := range d.species {
+			byClass[spe.Class] = append(byClass[spe.Class], id)
+		}
+		classes := make([]Class, 0, len(byClass))
+		for class := range byClass {
+			classes = append(classes, class)
+		}
+		sort.Slice(classes, func(i, j int) bool {
+			return classes[i] < classes[j]
+		})
+		next := 0
+		for _, class := range classes {
+			ids := byClass[class]
+			r.Shuffle(len(ids), func(i, j int) {
+				ids[i], ids[j] = ids[j], ids[i]
+			})
+			for _, id := range ids {
+				folds[next] = append(folds[next], id)
+				next = (next + 1) % numOfFolds
+			}
+		}
+	default:
+		return nil, fmt.Errorf("classifier: unknown cross-validation mode "+
+			"%d", opts.Mode)
+	}
+	return folds, nil
+}
+
+// meanStd() returns the mean and the sample standard deviation of values.
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) == 1 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values) - 1)
+	return mean, math.Sqrt(variance)
+}
+
+// BestResult() returns the result with the highest mean accuracy. Ties go
+// to the smallest k.
+func BestResult(results []CVResult) CVResult {
+	best := CVResult{Mean: -1}
+	for _, result := range results {
+		if result.Mean > best.Mean {
+			best = result
+		}
+	}
+	return best
+}
+
+// WriteCVTable() writes the mean and standard deviation of the accuracy
+// for every k.
+func WriteCVTable(out io.Writer, results []CVResult) {
+	fmt.Fprintf(out, "%-6s%8s%12s%12s\n", "k", "folds", "mean", "std")
+	for _, result := range results {
+		fmt.Fprintf(out, "%-6d%8d%12.4f%12.4f\n", result.K,
+			len(result.Accuracies), result.Mean, result.Std)
+	}
+}
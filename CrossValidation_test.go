@@ -0,0 +1,166 @@
+package classifier
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Folds must split every species into exactly one fold, and differ in size
+// by one species at most.
+func TestMakeFolds(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	n := len(d.species)
+	tests := []struct {
+		name	string
+		opts	CVOptions
+		folds	int
+	}{
+		{"kfold", CVOptions{Mode: KFold, Folds: 10, Seed: 1}, 10},
+		{"kfold uneven", CVOptions{Mode: KFold, Folds: 7, Seed: 1}, 7},
+		{"stratified", CVOptions{Mode: StratifiedKFold, Folds: 5, Seed: 1},
+			5},
+		{"loo", CVOptions{Mode: LeaveOneOut, Folds: 3, Seed: 1}, n},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folds, err := makeFolds(d, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(folds) != test.folds {
+				t.Fatalf("got %d folds, want %d", len(folds), test.folds)
+			}
+			ids := make([]int, 0, n)
+			for _, fold := range folds {
+				if size := len(fold); size < n/test.folds ||
+					size > (n+test.folds-1)/test.folds {
+					t.Errorf("fold of %d species out of %d", size, n)
+				}
+				ids = append(ids, fold...)
+			}
+			sort.Ints(ids)
+			for i, id := range ids {
+				if id != i {
+					t.Fatalf("species %d is in no fold or in several", i)
+				}
+			}
+			again, err := makeFolds(d, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(folds, again) {
+				t.Error("the same seed gives different folds")
+			}
+		})
+	}
+}
+
+// Stratified folds must hold every class in proportion.
+func TestMakeFoldsStratified(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	folds, err := makeFolds(d, CVOptions{Mode: StratifiedKFold, Folds: 5,
+		Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fold := range folds {
+		perClass := make(map[Class]int)
+		for _, id := range fold {
+			perClass[d.species[id].Class]++
+		}
+		for _, class := range d.Classes() {
+			want := d.ClassCount(class) / len(folds)
+			if got := perClass[class]; got < want || got > want+1 {
+				t.Errorf("%d species of %s in a fold, want %d", got, class,
+					want)
+			}
+		}
+	}
+}
+
+func TestMakeFoldsInvalid(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	for _, folds := range []int{0, 1, len(d.species) + 1} {
+		if _, err := makeFolds(d, CVOptions{Mode: KFold,
+			Folds: folds}); err == nil {
+			t.Errorf("%d folds did not fail", folds)
+		}
+	}
+}
+
+func TestCVModeFlag(t *testing.T) {
+	for _, name := range cvModeNames {
+		var m CVMode
+		if err := m.Set(name); err != nil || m.String() != name {
+			t.Errorf("Set(%q) gives %s, %v", name, m, err)
+		}
+	}
+	var m CVMode
+	if err := m.Set("bootstrap"); err == nil {
+		t.Error("Set(\"bootstrap\") did not fail")
+	}
+}
+
+// Every genus of the data set has a single species, so a classifier can
+// only predict a species right if it sees the species itself.
+func TestCrossValidateLeavesFoldOut(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	species := make([]Species, 0)
+	for _, genus := range []Class{"GenusA", "GenusB", "GenusC", "GenusD"} {
+		species = append(species, newTestSpecies(string(genus), genus,
+			randomSequence(r, 300), DefaultKmerConfig))
+	}
+	d := NewRawData(&species, DefaultKmerConfig)
+	opts := CVOptions{Mode: LeaveOneOut, Seed: 1, MinK: 1, MaxK: 3}
+	for _, name := range Backends() {
+		results, err := CrossValidate(name, d, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range results {
+			if result.Mean != 0 {
+				t.Errorf("%s, k = %d: accuracy %.2f, want 0", name,
+					result.K, result.Mean)
+			}
+		}
+	}
+}
+
+func TestPredictNeighboursExclude(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	kc, err := KNNLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := d.species[0]
+	ks := []int{1, 5}
+	all, err := kc.PredictNeighbours(query.Features(), ks, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, err := kc.PredictNeighbours(query.Features(), ks,
+		func(id int) bool { return id == 0 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	genus, err := kc.PredictNeighbours(query.Features(), ks,
+		func(id int) bool { return d.species[id].Class == query.Class })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, k := range ks {
+		if all[i].Score != float64(len(query.Words)) {
+			t.Errorf("k = %d: best score %.0f, want the %d words of the "+
+				"query itself", k, all[i].Score, len(query.Words))
+		}
+		if self[i].Score >= all[i].Score {
+			t.Errorf("k = %d: the query was not left out", k)
+		}
+		if genus[i].Class == query.Class {
+			t.Errorf("k = %d: predicted %s from species left out", k,
+				query.Class)
+		}
+	}
+}
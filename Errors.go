@@ -0,0 +1,116 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+var (
+	// ErrMalformedHeader is returned, wrapped in a *HeaderError, when an
+	// identity line does not have the form of its header format, e.g. 
+	// ">ID Domain;...;Genus;Species" for SILVA.
+	ErrMalformedHeader = errors.New("classifier: malformed identity line")
+	// ErrMalformedRecord is returned when a FASTQ record is cut short.
+	ErrMalformedRecord = errors.New("classifier: malformed sequence record")
+	// ErrModelNotFound is returned when a saved classifier does not exist.
+	ErrModelNotFound = errors.New("classifier: model not found")
+	// ErrUntrainedModel is returned when a classifier has not learned any
+	// species yet.
+	ErrUntrainedModel = errors.New("classifier: model not trained")
+	// ErrInvalidConfig is returned for k-mer settings out of range.
+	ErrInvalidConfig = errors.New("classifier: invalid k-mer settings")
+	// ErrInvalidModel is returned for naive Bayes model settings out of 
+	// range, or a model the classifier lacks the counts for.
+	ErrInvalidModel = errors.New("classifier: invalid naive Bayes model")
+	// ErrUnknownSpecies is returned when a classifier is asked to unlearn a
+	// species it has not learned.
+	ErrUnknownSpecies = errors.New("classifier: species not learned")
+	// ErrInvalidFilter is returned for a reference filter specification out
+	// of range.
+	ErrInvalidFilter = errors.New("classifier: invalid filter")
+	// ErrConfigMismatch is returned when a classifier learns data whose
+	// words were generated with other k-mer settings.
+	ErrConfigMismatch = errors.New("classifier: k-mer settings do not match")
+	// ErrInvalidK is returned when fewer than one neighbour is asked for.
+	ErrInvalidK = errors.New("classifier: k must be at least 1")
+	// ErrUnknownBackend is returned when no classifier is registered under
+	// a name.
+	ErrUnknownBackend = errors.New("classifier: unknown backend")
+	// ErrUnknownFormat is returned when no header format is registered 
+	// under a name.
+	ErrUnknownFormat = errors.New("classifier: unknown header format")
+	// ErrMalformedTaxonomy is returned when a line of a taxonomy file 
+	// cannot be parsed.
+	ErrMalformedTaxonomy = errors.New("classifier: malformed taxonomy file")
+	// ErrMissingTaxonomy is returned when the IDs of a taxonomy file and of
+	// the sequences it describes do not match.
+	ErrMissingTaxonomy = errors.New("classifier: taxonomy IDs do not match "+
+		"the sequences")
+)
+
+// A HeaderError records an identity line that could not be parsed, and the
+// line of the file it was found at.
+type HeaderError struct {
+	Line		int
+	Header		string
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("%v at line %d: %q", ErrMalformedHeader, e.Line,
+		e.Header)
+}
+
+func (e *HeaderError) Unwrap() error {
+	return ErrMalformedHeader
+}
+
+// openModel() opens a saved classifier, reporting a missing file as
+// ErrModelNotFound.
+func openModel(fileName string) (*os.File, error) {
+	file, err := os.Open(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotFound, fileName)
+	}
+	return file, err
+}
+
+// createFile() creates a file, lets write fill it and closes it, returning
+// the first error.
+func createFile(fileName string, write func(io.Writer) error) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// countingWriter counts the bytes written through it, for WriteTo().
+type countingWriter struct {
+	w		io.Writer
+	n		int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// countingReader counts the bytes read through it, for ReadFrom().
+type countingReader struct {
+	r		io.Reader
+	n		int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
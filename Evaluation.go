@@ -0,0 +1,289 @@
+package classifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A ConfusionMatrix counts, for every true class, how often each class was
+// predicted.
+type ConfusionMatrix struct {
+	counts		map[Class]map[Class]int
+	actual		map[Class]int
+	predicted	map[Class]int
+	total		int
+}
+
+func NewConfusionMatrix() *ConfusionMatrix {
+	return &ConfusionMatrix{
+		counts:		make(map[Class]map[Class]int),
+		actual:		make(map[Class]int),
+		predicted:	make(map[Class]int),
+	}
+}
+
+// Add() counts one prediction.
+func (m *ConfusionMatrix) Add(actual, predicted Class) {
+	row, isExist := m.counts[actual]
+	if !isExist {
+		row = make(map[Class]int)
+		m.counts[actual] = row
+	}
+	row[predicted]++
+	m.actual[actual]++
+	m.predicted[predicted]++
+	m.total++
+}
+
+// Count() returns how often a species of class actual was predicted as
+// predicted.
+func (m *ConfusionMatrix) Count(actual, predicted Class) int {
+	return m.counts[actual][predicted]
+}
+
+// Total() returns the number of predictions counted.
+func (m *ConfusionMatrix) Total() int {
+	return m.total
+}
+
+// Correct() returns the number of correct predictions.
+func (m *ConfusionMatrix) Correct() int {
+	correct := 0
+	for class, row := range m.counts {
+		correct += row[class]
+	}
+	return correct
+}
+
+// Classes() returns every class that is a true or a predicted class, sorted.
+func (m *ConfusionMatrix) Classes() []Class {
+	classes := make([]Class, 0, len(m.actual))
+	for class := range m.actual {
+		classes = append(classes, class)
+	}
+	for class := range m.predicted {
+		if _, isExist := m.actual[class]; !isExist {
+			classes = append(classes, class)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	return classes
+}
+
+// ClassMetrics are the precision, recall and F1 score of one class, and its
+// support (the number of species of the class in the test data).
+type ClassMetrics struct {
+	Class		Class		`json:"class"`
+	Precision	float64		`json:"precision"`
+	Recall		float64		`json:"recall"`
+	F1			float64		`json:"f1"`
+	Support		int			`json:"support"`
+}
+
+// Metrics() returns the metrics of every class of Classes(). A precision or
+// recall whose denominator is zero is reported as 0.
+func (m *ConfusionMatrix) Metrics() []ClassMetrics {
+	classes := m.Classes()
+	metrics := make([]ClassMetrics, len(classes))
+	for i, class := range classes {
+		tp := m.Count(class, class)
+		metrics[i] = newClassMetrics(class, tp, m.predicted[class],
+			m.actual[class])
+	}
+	return metrics
+}
+
+func newClassMetrics(class Class, tp, predicted, actual int) ClassMetrics {
+	cm := ClassMetrics{Class: class, Support: actual}
+	cm.Precision = ratio(tp, predicted)
+	cm.Recall = ratio(tp, actual)
+	if cm.Precision+cm.Recall > 0 {
+		cm.F1 = 2 * cm.Precision * cm.Recall / (cm.Precision + cm.Recall)
+	}
+	return cm
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// MacroAverage() returns the unweighted mean of the metrics of the classes
+// found in the test data. Its support is the number of predictions.
+func (m *ConfusionMatrix) MacroAverage() ClassMetrics {
+	avg := ClassMetrics{Class: "macro", Support: m.total}
+	n := 0
+	for _, cm := range m.Metrics() {
+		if cm.Support == 0 {
+			continue
+		}
+		avg.Precision += cm.Precision
+		avg.Recall += cm.Recall
+		avg.F1 += cm.F1
+		n++
+	}
+	if n > 0 {
+		avg.Precision /= float64(n)
+		avg.Recall /= float64(n)
+		avg.F1 /= float64(n)
+	}
+	return avg
+}
+
+// MicroAverage() returns the metrics computed from the counts of all
+// classes pooled. When every species gets a prediction, they all equal the
+// accuracy.
+func (m *ConfusionMatrix) MicroAverage() ClassMetrics {
+	return newClassMetrics("micro", m.Correct(), m.total, m.total)
+}
+
+// An Evaluation holds the results of testing one classifier: its confusion
+// matrix, its accuracy at every rank of the lineage and the number of test 
+// species predicted as NoHit. A NoHit prediction is counted as wrong, and 
+// appears in the matrix as a predicted class.
+type Evaluation struct {
+	Classifier		string
+	Matrix			*ConfusionMatrix
+	RankTested		[NumRanks]int
+	RankCorrect		[NumRanks]int
+	NoHits			int
+}
+
+func NewEvaluation(classifier string) *Evaluation {
+	return &Evaluation{Classifier: classifier, Matrix: NewConfusionMatrix()}
+}
+
+// Add() counts the prediction of one test species.
+func (ev *Evaluation) Add(spe *Species, p Prediction) {
+	ev.Matrix.Add(spe.Class, p.Class)
+	if p.Class == NoHit {
+		ev.NoHits++
+	}
+	for r := 0; r < NumRanks; r++ {
+		if spe.Lineage[r] == "" {
+			continue
+		}
+		ev.RankTested[r]++
+		if p.Lineage[r] == spe.Lineage[r] {
+			ev.RankCorrect[r]++
+		}
+	}
+}
+
+// Accuracy() returns the fraction of correct predictions.
+func (ev *Evaluation) Accuracy() float64 {
+	return ratio(ev.Matrix.Correct(), ev.Matrix.Total())
+}
+
+// RankAccuracy() returns the fraction of the species having a taxon at a
+// rank whose taxon was predicted correctly.
+func (ev *Evaluation) RankAccuracy(r Rank) float64 {
+	return ratio(ev.RankCorrect[r], ev.RankTested[r])
+}
+
+// WriteMetricsTSV() writes the metrics of every class of every evaluation,
+// followed by their macro and micro averages, as tab-separated rows
+// (classifier, class, precision, recall, F1, support) after a header line.
+func WriteMetricsTSV(w io.Writer, evals []*Evaluation) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintln(writer, "classifier\tclass\tprecision\trecall\tf1\tsupport")
+	for _, ev := range evals {
+		metrics := append(ev.Matrix.Metrics(), ev.Matrix.MacroAverage(),
+			ev.Matrix.MicroAverage())
+		for _, cm := range metrics {
+			fmt.Fprintf(writer, "%s\t%s\t%.4f\t%.4f\t%.4f\t%d\n",
+				ev.Classifier, cm.Class, cm.Precision, cm.Recall, cm.F1,
+				cm.Support)
+		}
+	}
+	return writer.Flush()
+}
+
+// WriteConfusionTSV() writes the non-zero cells of the confusion matrix of
+// every evaluation as tab-separated rows (classifier, true class, predicted
+// class, count) after a header line.
+func WriteConfusionTSV(w io.Writer, evals []*Evaluation) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintln(writer, "classifier\tactual\tpredicted\tcount")
+	for _, ev := range evals {
+		for _, cell := range ev.Matrix.cells() {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%d\n", ev.Classifier,
+				cell.Actual, cell.Predicted, cell.Count)
+		}
+	}
+	return writer.Flush()
+}
+
+type confusionCell struct {
+	Actual		Class	`json:"actual"`
+	Predicted	Class	`json:"predicted"`
+	Count		int		`json:"count"`
+}
+
+// cells() returns the non-zero cells of the matrix, sorted by true and then
+// predicted class.
+func (m *ConfusionMatrix) cells() []confusionCell {
+	classes := m.Classes()
+	cells := make([]confusionCell, 0)
+	for _, actual := range classes {
+		for _, predicted := range classes {
+			if n := m.Count(actual, predicted); n > 0 {
+				cells = append(cells, confusionCell{actual, predicted, n})
+			}
+		}
+	}
+	return cells
+}
+
+type rankAccuracy struct {
+	Rank		string		`json:"rank"`
+	Tested		int			`json:"tested"`
+	Accuracy	float64		`json:"accuracy"`
+}
+
+type jsonEvaluation struct {
+	Classifier		string			`json:"classifier"`
+	Tested			int				`json:"tested"`
+	Accuracy		float64			`json:"accuracy"`
+	NoHits			int				`json:"no_hits"`
+	Classes			[]ClassMetrics	`json:"classes"`
+	Macro			ClassMetrics	`json:"macro"`
+	Micro			ClassMetrics	`json:"micro"`
+	Ranks			[]rankAccuracy	`json:"ranks"`
+	Confusion		[]confusionCell	`json:"confusion"`
+}
+
+// WriteEvaluationJSON() writes the evaluations, with their metrics, rank
+// accuracies and confusion matrices, as an indented JSON array. Classes are
+// sorted, so the same results are always written identically.
+func WriteEvaluationJSON(w io.Writer, evals []*Evaluation) error {
+	out := make([]jsonEvaluation, len(evals))
+	for i, ev := range evals {
+		out[i] = jsonEvaluation{
+			Classifier:	ev.Classifier,
+			Tested:		ev.Matrix.Total(),
+			Accuracy:	ev.Accuracy(),
+			NoHits:		ev.NoHits,
+			Classes:	ev.Matrix.Metrics(),
+			Macro:		ev.Matrix.MacroAverage(),
+			Micro:		ev.Matrix.MicroAverage(),
+			Ranks:		make([]rankAccuracy, 0, NumRanks),
+			Confusion:	ev.Matrix.cells(),
+		}
+		for r := 0; r < NumRanks; r++ {
+			if ev.RankTested[r] == 0 {
+				continue
+			}
+			out[i].Ranks = append(out[i].Ranks, rankAccuracy{
+				Rank(r).String(), ev.RankTested[r], ev.RankAccuracy(Rank(r))})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
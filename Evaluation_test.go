@@ -0,0 +1,131 @@
+package classifier
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// testMatrix() returns the matrix of 8 predictions of the classes A, B and
+// C, where C is never predicted and D is predicted but never true.
+func testMatrix() *ConfusionMatrix {
+	m := NewConfusionMatrix()
+	pairs := []struct {
+		actual, predicted	Class
+		n					int
+	}{
+		{"A", "A", 3},
+		{"A", "B", 1},
+		{"B", "B", 2},
+		{"C", "B", 1},
+		{"B", "D", 1},
+	}
+	for _, pair := range pairs {
+		for i := 0; i < pair.n; i++ {
+			m.Add(pair.actual, pair.predicted)
+		}
+	}
+	return m
+}
+
+// sameMetrics() reports whether two metrics are equal up to rounding.
+func sameMetrics(a, b ClassMetrics) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return a.Class == b.Class && a.Support == b.Support &&
+		near(a.Precision, b.Precision) && near(a.Recall, b.Recall) &&
+		near(a.F1, b.F1)
+}
+
+func TestConfusionMatrixCounts(t *testing.T) {
+	m := testMatrix()
+	tests := []struct {
+		actual, predicted	Class
+		want				int
+	}{
+		{"A", "A", 3},
+		{"A", "B", 1},
+		{"B", "B", 2},
+		{"B", "A", 0},
+		{"C", "B", 1},
+		{"C", "C", 0},
+		{"B", "D", 1},
+		{"D", "D", 0},
+		{"E", "A", 0},
+	}
+	for _, test := range tests {
+		if got := m.Count(test.actual, test.predicted); got != test.want {
+			t.Errorf("Count(%s, %s) = %d, want %d", test.actual,
+				test.predicted, got, test.want)
+		}
+	}
+	if m.Total() != 8 || m.Correct() != 5 {
+		t.Errorf("total %d and correct %d, want 8 and 5", m.Total(),
+			m.Correct())
+	}
+	want := []Class{"A", "B", "C", "D"}
+	if got := m.Classes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Classes() = %v, want %v", got, want)
+	}
+}
+
+func TestConfusionMatrixMetrics(t *testing.T) {
+	m := testMatrix()
+	want := []ClassMetrics{
+		{"A", 1, 0.75, 6.0 / 7, 4},
+		{"B", 0.5, 2.0 / 3, 4.0 / 7, 3},
+		// C is never predicted, and D is never true: their precision and
+		// recall divide by zero.
+		{"C", 0, 0, 0, 1},
+		{"D", 0, 0, 0, 0},
+	}
+	got := m.Metrics()
+	if len(got) != len(want) {
+		t.Fatalf("got %d classes, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !sameMetrics(got[i], want[i]) {
+			t.Errorf("got %+v, want %+v", got[i], want[i])
+		}
+	}
+}
+
+func TestConfusionMatrixAverages(t *testing.T) {
+	tests := []struct {
+		name	string
+		m		*ConfusionMatrix
+		macro	ClassMetrics
+		micro	ClassMetrics
+	}{
+		// D has no support, so it is left out of the macro average.
+		{"test matrix", testMatrix(),
+			ClassMetrics{"macro", 0.5, 17.0 / 36, 10.0 / 21, 8},
+			ClassMetrics{"micro", 0.625, 0.625, 0.625, 8}},
+		{"empty", NewConfusionMatrix(), ClassMetrics{Class: "macro"},
+			ClassMetrics{Class: "micro"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.m.MacroAverage(); !sameMetrics(got, test.macro) {
+				t.Errorf("macro %+v, want %+v", got, test.macro)
+			}
+			if got := test.m.MicroAverage(); !sameMetrics(got, test.micro) {
+				t.Errorf("micro %+v, want %+v", got, test.micro)
+			}
+		})
+	}
+}
+
+func TestWriteConfusionTSV(t *testing.T) {
+	var buf bytes.Buffer
+	ev := &Evaluation{Classifier: "NBC", Matrix: testMatrix()}
+	if err := WriteConfusionTSV(&buf, []*Evaluation{ev}); err != nil {
+		t.Fatal(err)
+	}
+	want := "classifier\tactual\tpredicted\tcount\n" +
+		"NBC\tA\tA\t3\nNBC\tA\tB\t1\nNBC\tB\tB\t2\nNBC\tB\tD\t1\n" +
+		"NBC\tC\tB\t1\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,471 @@
+package classifier
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A FilterSpec selects the records of a reference FASTA file kept in a data
+// set (see ReadFASTAFile()). A record is kept if its domain is one of
+// Domains (any domain if Domains is empty or the domain is not known), it
+// matches one of the Include patterns (if any) and none of the Exclude 
+// patterns, it has at least MinLength bases and at most a fraction 
+// MaxAmbiguous of them are IUPAC ambiguity codes or other non-bases. Of the
+// records passing these rules, every SampleEvery-th one is kept; or, if 
+// TargetSize is set, exactly TargetSize of them are drawn at random with 
+// Seed (all of them if there are fewer). With Dereplicate, only the first 
+// of identical sequences (case, U and T, and alignment gaps aside) passes 
+// the rules.
+//
+// Classes are balanced if MaxPerClass or MinPerClass is set: after the 
+// sampling, classes with more than MaxPerClass records keep MaxPerClass of 
+// them, and classes with fewer than MinPerClass records get records that 
+// passed the rules back, up to MinPerClass if they have that many. The 
+// records dropped or added are drawn at random with Seed.
+//
+// A pattern is a shell glob (see path.Match) matched against every taxon
+// of the taxonomy, e.g. "Lactobacill*", or against the taxon of one rank
+// only when prefixed by the rank, e.g. "genus=uncultured". The ranks are
+// those of Rank, and "species" for the species name.
+type FilterSpec struct {
+	Domains			[]string
+	Include			[]string
+	Exclude			[]string
+	MinLength		int
+	MaxAmbiguous	float64
+	SampleEvery		int
+	TargetSize		int
+	Dereplicate		bool
+	MaxPerClass		int
+	MinPerClass		int
+	Seed			int64
+}
+
+// DefaultFilterSpec is the selection the transformer always made: every
+// 100th bacterial record whose genus is not "uncultured".
+var DefaultFilterSpec = FilterSpec{
+	Domains:		[]string{"Bacteria"},
+	Exclude:		[]string{"genus=uncultured"},
+	MaxAmbiguous:	1,
+	SampleEvery:	100,
+	Seed:			1,
+}
+
+// Check() returns an error wrapping ErrInvalidFilter if the specification
+// is out of range or has a malformed pattern.
+func (spec FilterSpec) Check() error {
+	if spec.MinLength < 0 || spec.MaxAmbiguous < 0 || spec.TargetSize < 0 ||
+		spec.MaxPerClass < 0 || spec.MinPerClass < 0 {
+		return fmt.Errorf("%w: negative limit", ErrInvalidFilter)
+	}
+	if spec.MaxPerClass > 0 && spec.MinPerClass > spec.MaxPerClass {
+		return fmt.Errorf("%w: the minimum per class is above the maximum",
+			ErrInvalidFilter)
+	}
+	if spec.SampleEvery < 1 {
+		return fmt.Errorf("%w: records must be sampled every 1 or more",
+			ErrInvalidFilter)
+	}
+	if spec.SampleEvery > 1 && spec.TargetSize > 0 {
+		return fmt.Errorf("%w: a sampling rate and a target size cannot "+
+			"both be given", ErrInvalidFilter)
+	}
+	for _, pattern := range append(append([]string(nil), spec.Include...),
+		spec.Exclude...) {
+		if _, _, err := parsePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// balances() tells if the classes are balanced, which needs all the records
+// passing the rules before any is kept.
+func (spec FilterSpec) balances() bool {
+	return spec.MaxPerClass > 0 || spec.MinPerClass > 0
+}
+
+// The rules of a FilterSpec, in the order they are applied.
+type filterRule int
+
+const (
+	domainRule filterRule = iota
+	includeRule
+	excludeRule
+	lengthRule
+	ambiguousRule
+	duplicateRule
+	samplingRule
+	numFilterRules
+)
+
+var filterRuleNames = [numFilterRules]string{"domain", "include",
+	"exclude", "length", "ambiguous", "duplicate", "sampling"}
+
+// A FilterReport counts the records read, the records kept and the records
+// discarded by each rule. A record is only counted for the first rule it
+// fails; records dropped or added back by balancing count for sampling. 
+// Classes holds the same counts for every class.
+type FilterReport struct {
+	Read		int
+	Kept		int
+	Discarded	[numFilterRules]int
+	Classes		map[Class]*ClassCount
+}
+
+// A ClassCount is the number of records of a class read, passing the rules
+// (Available) and kept.
+type ClassCount struct {
+	Read		int
+	Available	int
+	Kept		int
+}
+
+func (r *FilterReport) class(class Class) *ClassCount {
+	if r.Classes == nil {
+		r.Classes = make(map[Class]*ClassCount)
+	}
+	cc, isExist := r.Classes[class]
+	if !isExist {
+		cc = new(ClassCount)
+		r.Classes[class] = cc
+	}
+	return cc
+}
+
+// WriteFilterReport() writes how many records each rule discarded.
+func WriteFilterReport(out io.Writer, r *FilterReport) {
+	fmt.Fprintf(out, "Records read: %d  kept: %d\n", r.Read, r.Kept)
+	for rule, name := range filterRuleNames {
+		fmt.Fprintf(out, "  discarded by %-12s%10d\n", name+":",
+			r.Discarded[rule])
+	}
+}
+
+// WriteClassSummary() writes the counts of every class, sorted, as 
+// tab-separated rows (class, read, available, kept) after a header line.
+func WriteClassSummary(w io.Writer, r *FilterReport) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintln(writer, "class\tread\tavailable\tkept")
+	classes := make([]Class, 0, len(r.Classes))
+	for class := range r.Classes {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	for _, class := range classes {
+		cc := r.Classes[class]
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\n", class, cc.Read, 
+			cc.Available, cc.Kept)
+	}
+	return writer.Flush()
+}
+
+// A recordFilter applies a FilterSpec to the records of one file. It holds
+// all the state of the selection, so that several files can be filtered at
+// once.
+type recordFilter struct {
+	spec		FilterSpec
+	report		FilterReport
+	passed		int
+	rand		*rand.Rand
+	// The records drawn so far when sampling a target size, with their
+	// position among the records passing the rules.
+	reservoir	[]Species
+	positions	[]int
+	// The hashes of the sequences seen, for dereplication.
+	sequences	map[[16]byte]bool
+	// The records passing the rules when balancing classes, by position in
+	// the file.
+	candidates	[]candidate
+}
+
+type candidate struct {
+	position	int
+	class		Class
+}
+
+func newRecordFilter(spec FilterSpec) (*recordFilter, error) {
+	if err := spec.Check(); err != nil {
+		return nil, err
+	}
+	return &recordFilter{spec: spec, rand: rand.New(rand.NewSource(
+		spec.Seed))}, nil
+}
+
+// pass() applies the rules to a record, and tells if it passes them.
+func (rf *recordFilter) pass(s *Species) bool {
+	rf.report.Read++
+	cc := rf.report.class(s.Class)
+	cc.Read++
+	rule := rf.rule(s)
+	if rule != numFilterRules {
+		rf.report.Discarded[rule]++
+		return false
+	}
+	rf.passed++
+	cc.Available++
+	return true
+}
+
+// add() applies the rules to a record. Without a target size, it tells if
+// the record is kept. With one, the record may be drawn into the reservoir
+// and add() returns false; the sample is returned by sample().
+func (rf *recordFilter) add(s Species) bool {
+	if !rf.pass(&s) {
+		return false
+	}
+	if rf.spec.TargetSize == 0 {
+		if rf.passed%rf.spec.SampleEvery != 0 {
+			rf.report.Discarded[samplingRule]++
+			return false
+		}
+		rf.report.Kept++
+		rf.report.class(s.Class).Kept++
+		return true
+	}
+	// Reservoir sampling: the i-th record replaces a random one of the
+	// sample with probability TargetSize / i.
+	if len(rf.reservoir) < rf.spec.TargetSize {
+		rf.reservoir = append(rf.reservoir, s)
+		rf.positions = append(rf.positions, rf.passed)
+		return false
+	}
+	if j := rf.rand.Intn(rf.passed); j < rf.spec.TargetSize {
+		rf.reservoir[j] = s
+		rf.positions[j] = rf.passed
+	}
+	return false
+}
+
+// sample() returns the records drawn for a target size, in the order of
+// the file, and completes the report.
+func (rf *recordFilter) sample() []Species {
+	order := make([]int, len(rf.reservoir))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return rf.positions[order[i]] < rf.positions[order[j]]
+	})
+	species := make([]Species, len(order))
+	for i, j := range order {
+		species[i] = rf.reservoir[j]
+		rf.report.class(species[i].Class).Kept++
+	}
+	rf.report.Kept += len(species)
+	rf.report.Discarded[samplingRule] += rf.passed - len(species)
+	return species
+}
+
+// collect() applies the rules to a record when balancing classes, and 
+// remembers it if it passes them.
+func (rf *recordFilter) collect(s Species) {
+	if rf.pass(&s) {
+		rf.candidates = append(rf.candidates, candidate{rf.report.Read, 
+			s.Class})
+	}
+}
+
+// balance() samples the records collected, balances their classes, and 
+// returns the positions in the file of the records kept.
+func (rf *recordFilter) balance() map[int]bool {
+	isSampled := make([]bool, len(rf.candidates))
+	if rf.spec.TargetSize > 0 {
+		for i, j := range rf.rand.Perm(len(rf.candidates)) {
+			if i == rf.spec.TargetSize {
+				break
+			}
+			isSampled[j] = true
+		}
+	} else {
+		for i := range isSampled {
+			isSampled[i] = (i+1)%rf.spec.SampleEvery == 0
+		}
+	}
+	byClass := make(map[Class][]int)
+	for i, c := range rf.candidates {
+		byClass[c.class] = append(byClass[c.class], i)
+	}
+	classes := make([]Class, 0, len(byClass))
+	for class := range byClass {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	selected := make(map[int]bool)
+	for _, class := range classes {
+		sampled, others := make([]int, 0), make([]int, 0)
+		for _, i := range byClass[class] {
+			if isSampled[i] {
+				sampled = append(sampled, i)
+			} else {
+				others = append(others, i)
+			}
+		}
+		if max := rf.spec.MaxPerClass; max > 0 && len(sampled) > max {
+			rf.shuffle(sampled)
+			sampled = sampled[:max]
+		}
+		if min := rf.spec.MinPerClass; len(sampled) < min {
+			rf.shuffle(others)
+			n := min - len(sampled)
+			if n > len(others) {
+				n = len(others)
+			}
+			sampled = append(sampled, others[:n]...)
+		}
+		for _, i := range sampled {
+			selected[rf.candidates[i].position] = true
+		}
+		rf.report.class(class).Kept += len(sampled)
+	}
+	rf.report.Kept += len(selected)
+	rf.report.Discarded[samplingRule] += rf.passed - len(selected)
+	return selected
+}
+
+func (rf *recordFilter) shuffle(ids []int) {
+	rf.rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+}
+
+// rule() returns the first rule a record fails, or numFilterRules if it
+// passes them all.
+func (rf *recordFilter) rule(s *Species) filterRule {
+	// Records of unknown domain, such as NCBI ones, pass the domain rule.
+	domain := string(s.Lineage[RankDomain])
+	if len(rf.spec.Domains) > 0 && domain != "" &&
+		!containsString(rf.spec.Domains, domain) {
+		return domainRule
+	}
+	if len(rf.spec.Include) > 0 && !matchesAny(rf.spec.Include, s) {
+		return includeRule
+	}
+	if matchesAny(rf.spec.Exclude, s) {
+		return excludeRule
+	}
+	length, ambiguous := countBases(s.Sequence)
+	if length < rf.spec.MinLength {
+		return lengthRule
+	}
+	if float64(ambiguous) > rf.spec.MaxAmbiguous*float64(length) {
+		return ambiguousRule
+	}
+	if rf.spec.Dereplicate {
+		if rf.sequences == nil {
+			rf.sequences = make(map[[16]byte]bool)
+		}
+		hash := hashSequence(s.Sequence)
+		if rf.sequences[hash] {
+			return duplicateRule
+		}
+		rf.sequences[hash] = true
+	}
+	return numFilterRules
+}
+
+// hashSequence() returns a 128-bit FNV hash of a sequence, ignoring case,
+// alignment gaps and the difference between U and T.
+func hashSequence(sequence string) [16]byte {
+	h := fnv.New128a()
+	buf := make([]byte, 0, len(sequence))
+	for i := 0; i < len(sequence); i++ {
+		c := sequence[i]
+		if c == '-' || c == '.' {
+			continue
+		}
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c == 'U' {
+			c = 'T'
+		}
+		buf = append(buf, c)
+	}
+	h.Write(buf)
+	var hash [16]byte
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// countBases() returns the number of bases of a sequence, alignment gaps
+// left out, and how many of them are not A, C, G, T or U.
+func countBases(sequence string) (int, int) {
+	length, ambiguous := 0, 0
+	for i := 0; i < len(sequence); i++ {
+		if sequence[i] == '-' || sequence[i] == '.' {
+			continue
+		}
+		length++
+		if baseCode[sequence[i]] < 0 {
+			ambiguous++
+		}
+	}
+	return length, ambiguous
+}
+
+// parsePattern() splits a pattern into its rank (-1 for any rank, NumRanks
+// for the species name) and its glob.
+func parsePattern(pattern string) (int, string, error) {
+	rank, glob := -1, pattern
+	if i := strings.Index(pattern, "="); i >= 0 {
+		name := pattern[:i]
+		glob = pattern[i+1:]
+		rank = -2
+		for r, rankName := range rankNames {
+			if name == rankName {
+				rank = r
+			}
+		}
+		if name == "species" {
+			rank = NumRanks
+		}
+		if rank == -2 {
+			return 0, "", fmt.Errorf("%w: unknown rank %q in pattern %q",
+				ErrInvalidFilter, name, pattern)
+		}
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return 0, "", fmt.Errorf("%w: malformed pattern %q",
+			ErrInvalidFilter, pattern)
+	}
+	return rank, glob, nil
+}
+
+// matchesAny() tells if the taxonomy of a record matches one of the
+// patterns, which have been checked already.
+func matchesAny(patterns []string, s *Species) bool {
+	for _, pattern := range patterns {
+		rank, glob, _ := parsePattern(pattern)
+		var taxa []string
+		switch {
+		case rank < 0:
+			taxa = strings.Split(s.Taxonomy, ";")
+		case rank == NumRanks:
+			taxa = []string{s.Name}
+		default:
+			taxa = []string{string(s.Lineage[rank])}
+		}
+		for _, taxon := range taxa {
+			if isMatch, _ := path.Match(glob, taxon); isMatch {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
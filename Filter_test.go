@@ -0,0 +1,270 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// filterSpecies() returns a record of the given taxa, species name and
+// sequence, as read from a SILVA file.
+func filterSpecies(id, taxa, name, sequence string) Species {
+	taxonomy := taxa + ";" + name
+	lineage := ParseLineage(taxonomy)
+	return Species{Id: id, Taxonomy: taxonomy, Name: name,
+		Sequence: sequence, Class: lineage[RankGenus], Lineage: lineage}
+}
+
+// ncbiSpecies() returns a record read from an NCBI header, which does not
+// give the domain.
+func ncbiSpecies(id, description, sequence string) Species {
+	var s Species
+	parseNCBI(id+" "+description, &s)
+	s.Sequence = sequence
+	return s
+}
+
+func TestFilterRules(t *testing.T) {
+	bacillus := "Bacteria;Firmicutes;Bacilli;Bacillales;Bacillaceae;Bacillus"
+	tests := []struct {
+		name	string
+		spec	FilterSpec
+		s		Species
+		want	filterRule
+	}{
+		{"kept", DefaultFilterSpec, filterSpecies("a", bacillus,
+			"Bacillus subtilis", "ACGT"), numFilterRules},
+		{"other domain", DefaultFilterSpec, filterSpecies("a",
+			"Archaea;Euryarchaeota;Methanobacteria;Methanobacteriales;"+
+				"Methanobacteriaceae;Methanobrevibacter", "Methanobrevibacter"+
+				" smithii", "ACGT"), domainRule},
+		{"any domain", FilterSpec{SampleEvery: 1, MaxAmbiguous: 1},
+			filterSpecies("a", "Archaea;Euryarchaeota", "Archaea sp.",
+				"ACGT"), numFilterRules},
+		{"unknown domain", DefaultFilterSpec, ncbiSpecies("a",
+			"Bacillus subtilis strain 168 16S ribosomal RNA", "ACGT"),
+			numFilterRules},
+		{"uncultured genus", DefaultFilterSpec, filterSpecies("a",
+			"Bacteria;Firmicutes;Bacilli;Bacillales;Bacillaceae;uncultured",
+			"uncultured bacterium", "ACGT"), excludeRule},
+		{"included", FilterSpec{Include: []string{"Bacill*"},
+			SampleEvery: 1, MaxAmbiguous: 1}, filterSpecies("a", bacillus,
+			"Bacillus subtilis", "ACGT"), numFilterRules},
+		{"not included", FilterSpec{Include: []string{"genus=Listeria"},
+			SampleEvery: 1, MaxAmbiguous: 1}, filterSpecies("a", bacillus,
+			"Bacillus subtilis", "ACGT"), includeRule},
+		{"species excluded", FilterSpec{Exclude: []string{"species=* sp."},
+			SampleEvery: 1, MaxAmbiguous: 1}, filterSpecies("a", bacillus,
+			"Bacillus sp.", "ACGT"), excludeRule},
+		{"too short", FilterSpec{MinLength: 5, SampleEvery: 1,
+			MaxAmbiguous: 1}, filterSpecies("a", bacillus,
+			"Bacillus subtilis", "AC-G.T"), lengthRule},
+		{"too ambiguous", FilterSpec{SampleEvery: 1, MaxAmbiguous: 0.25},
+			filterSpecies("a", bacillus, "Bacillus subtilis", "ACNNGT"),
+			ambiguousRule},
+		{"ambiguous enough", FilterSpec{SampleEvery: 1, MaxAmbiguous: 0.25},
+			filterSpecies("a", bacillus, "Bacillus subtilis", "ACNGT"),
+			numFilterRules},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rf, err := newRecordFilter(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rf.rule(&test.s); got != test.want {
+				t.Errorf("rule() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterSpecCheck(t *testing.T) {
+	tests := []struct {
+		name	string
+		spec	FilterSpec
+	}{
+		{"negative length", FilterSpec{MinLength: -1, SampleEvery: 1}},
+		{"no sampling", FilterSpec{}},
+		{"rate and size", FilterSpec{SampleEvery: 2, TargetSize: 10}},
+		{"minimum above maximum", FilterSpec{SampleEvery: 1,
+			MaxPerClass: 2, MinPerClass: 3}},
+		{"unknown rank", FilterSpec{Include: []string{"strain=x"},
+			SampleEvery: 1}},
+		{"malformed glob", FilterSpec{Exclude: []string{"[a"},
+			SampleEvery: 1}},
+	}
+	for _, test := range tests {
+		if err := test.spec.Check(); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%s: got %v, want ErrInvalidFilter", test.name, err)
+		}
+	}
+	if err := DefaultFilterSpec.Check(); err != nil {
+		t.Errorf("default specification: %v", err)
+	}
+}
+
+// filterRecords() passes n records through a filter, of which every
+// tenth has the wrong domain, and returns the ids of the records kept,
+// which sort in the order of the records.
+func filterRecords(rf *recordFilter, n int) []string {
+	kept := make([]string, 0)
+	for i := 0; i < n; i++ {
+		taxonomy := "Bacteria;Firmicutes"
+		if i%10 == 9 {
+			taxonomy = "Archaea;Euryarchaeota"
+		}
+		s := filterSpecies(fmt.Sprintf("%03d", i), taxonomy, "", "ACGT")
+		if rf.add(s) {
+			kept = append(kept, s.Id)
+		}
+	}
+	if rf.spec.TargetSize > 0 {
+		for _, s := range rf.sample() {
+			kept = append(kept, s.Id)
+		}
+	}
+	return kept
+}
+
+func TestFilterSampling(t *testing.T) {
+	spec := DefaultFilterSpec
+	spec.SampleEvery = 4
+	rf, err := newRecordFilter(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := filterRecords(rf, 20)
+	// 18 records pass the rules, and every fourth of them is kept.
+	want := []string{"003", "007", "012", "016"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+	var discarded [numFilterRules]int
+	discarded[domainRule] = 2
+	discarded[samplingRule] = 14
+	if rf.report.Read != 20 || rf.report.Kept != 4 ||
+		rf.report.Discarded != discarded {
+		t.Errorf("report %+v", rf.report)
+	}
+}
+
+func TestFilterTargetSize(t *testing.T) {
+	for _, n := range []int{5, 200} {
+		spec := DefaultFilterSpec
+		spec.SampleEvery, spec.TargetSize = 1, 10
+		rf, err := newRecordFilter(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := filterRecords(rf, n)
+		passed := n - n/10
+		want := spec.TargetSize
+		if passed < want {
+			want = passed
+		}
+		if len(got) != want {
+			t.Errorf("%d records: kept %d, want %d", n, len(got), want)
+		}
+		if !sort.StringsAreSorted(got) {
+			t.Errorf("%d records: sample %v not in file order", n, got)
+		}
+		if rf.report.Read != n || rf.report.Kept != len(got) ||
+			rf.report.Discarded[samplingRule] != passed-len(got) {
+			t.Errorf("%d records: report %+v", n, rf.report)
+		}
+		again, err := newRecordFilter(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same := filterRecords(again, n); !reflect.DeepEqual(got, same) {
+			t.Errorf("%d records: the same seed drew %v and %v", n, got,
+				same)
+		}
+	}
+}
+
+func TestFilterDereplicate(t *testing.T) {
+	spec := FilterSpec{SampleEvery: 1, MaxAmbiguous: 1, Dereplicate: true}
+	rf, err := newRecordFilter(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := make([]string, 0)
+	for i, sequence := range []string{"ACGU", "acgt", "AC-GT", "ACGA",
+		"ACGT."} {
+		s := filterSpecies(fmt.Sprint(i), "Bacteria;Firmicutes",
+			"Firmicutes sp.", sequence)
+		if rf.add(s) {
+			kept = append(kept, s.Id)
+		}
+	}
+	if want := []string{"0", "3"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+	if rf.report.Discarded[duplicateRule] != 3 {
+		t.Errorf("%d duplicates, want 3", rf.report.Discarded[duplicateRule])
+	}
+}
+
+func TestFilterBalance(t *testing.T) {
+	sizes := map[Class]int{"GenusA": 10, "GenusB": 2, "GenusC": 5}
+	tests := []struct {
+		name		string
+		spec		FilterSpec
+		want		map[Class]int
+	}{
+		{"capped", FilterSpec{SampleEvery: 1, MaxPerClass: 4},
+			map[Class]int{"GenusA": 4, "GenusB": 2, "GenusC": 4}},
+		{"topped up", FilterSpec{SampleEvery: 5, MinPerClass: 3},
+			map[Class]int{"GenusA": 3, "GenusB": 2, "GenusC": 3}},
+		{"target size", FilterSpec{SampleEvery: 1, TargetSize: 8,
+			MaxPerClass: 3, MinPerClass: 2},
+			nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.spec.MaxAmbiguous, test.spec.Seed = 1, 1
+			rf, err := newRecordFilter(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			classOf := make(map[int]Class)
+			for _, class := range []Class{"GenusA", "GenusB", "GenusC"} {
+				for i := 0; i < sizes[class]; i++ {
+					rf.collect(filterSpecies(fmt.Sprint(i),
+						"Bacteria;Firmicutes;"+string(class),
+						string(class)+" sp.", "ACGT"))
+					classOf[rf.report.Read] = class
+				}
+			}
+			got := make(map[Class]int)
+			for position := range rf.balance() {
+				got[classOf[position]]++
+			}
+			for class, n := range got {
+				if n < test.spec.MinPerClass && n < sizes[class] ||
+					test.spec.MaxPerClass > 0 && n > test.spec.MaxPerClass {
+					t.Errorf("%d records of %s kept", n, class)
+				}
+			}
+			if test.want != nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("kept %v, want %v", got, test.want)
+			}
+			kept := 0
+			for class, cc := range rf.report.Classes {
+				if cc.Kept != got[class] || cc.Available != sizes[class] {
+					t.Errorf("%s: report %+v, kept %d", class, *cc,
+						got[class])
+				}
+				kept += cc.Kept
+			}
+			if rf.report.Kept != kept ||
+				rf.report.Discarded[samplingRule] != 17-kept {
+				t.Errorf("report %+v", rf.report)
+			}
+		})
+	}
+}
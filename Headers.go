@@ -0,0 +1,252 @@
+package classifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A HeaderFormat parses the identity line of a reference record, without
+// its ">", into the Id, Taxonomy, Class, Name and Lineage of a species, and
+// reports whether the line had the form of the format. It leaves the species
+// alone otherwise. Whatever the database, the lineage is normalized to the
+// ranks from domain to genus, and the taxonomy is written as a SILVA
+// taxonomy, so that data sets and classifiers do not depend on the database
+// the references came from.
+type HeaderFormat func(header string, s *Species) bool
+
+var headerFormats = map[string]HeaderFormat{
+	"silva":		parseSILVA,
+	"rdp":			parseRDP,
+	"greengenes":	parseGreengenes,
+	"gtdb":			parseGTDB,
+	"unite":		parseUNITE,
+	"ncbi":			parseNCBI,
+}
+
+// The formats AutoFormat() tries, the most specific first. NCBI is left
+// out, as any header with a description would pass for one.
+var detectedFormats = []string{"gtdb", "greengenes", "unite", "rdp", "silva"}
+
+// RegisterHeaderFormat() makes a header format available by name.
+// AutoFormat() tries it after the built-in formats.
+func RegisterHeaderFormat(name string, format HeaderFormat) {
+	if _, isExist := headerFormats[name]; isExist || name == "auto" {
+		panic("classifier: header format " + name + " registered twice")
+	}
+	headerFormats[name] = format
+	detectedFormats = append(detectedFormats, name)
+}
+
+// HeaderFormats() returns the names of the header formats, sorted, with
+// "auto" for AutoFormat().
+func HeaderFormats() []string {
+	names := []string{"auto"}
+	for name := range headerFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupHeaderFormat() returns the named header format; "auto" and "" give
+// AutoFormat().
+func LookupHeaderFormat(name string) (HeaderFormat, error) {
+	if name == "auto" || name == "" {
+		return AutoFormat, nil
+	}
+	format, isExist := headerFormats[name]
+	if !isExist {
+		return nil, fmt.Errorf("%w: %q (known formats: %v)",
+			ErrUnknownFormat, name, HeaderFormats())
+	}
+	return format, nil
+}
+
+// AutoFormat() parses a header with the first format it has the form of,
+// so that the records of a file may even come from several databases.
+func AutoFormat(header string, s *Species) bool {
+	for _, name := range detectedFormats {
+		if headerFormats[name](header, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitHeader() splits a header into its first word, the Id, and the rest.
+func splitHeader(header string) (string, string) {
+	i := strings.IndexAny(header, " \t")
+	if i < 0 {
+		return header, ""
+	}
+	return header[:i], strings.TrimSpace(header[i+1:])
+}
+
+// parseSILVA() parses "ID Domain;...;Genus;Species". The taxa between the
+// domain and the genus are placed as by ParseLineage().
+func parseSILVA(header string, s *Species) bool {
+	var parts []string = strings.Split(header, ";")
+	length := len(parts)
+	var part_1 []string = strings.Split(parts[0], " ")
+	if len(part_1) != 2 || length < 2 {
+		return false
+	}
+	taxa := append([]string{part_1[1]}, parts[1:length-1]...)
+	return s.setLineage(part_1[0], lineageOfTaxa(taxa), parts[length-1])
+}
+
+// parseGTDB() parses "ID d__Bacteria;p__...;g__Genus;s__Genus species",
+// followed by the optional "[key=value]" fields of the GTDB releases.
+func parseGTDB(header string, s *Species) bool {
+	id, taxonomy := splitHeader(header)
+	if i := strings.Index(taxonomy, " ["); i >= 0 {
+		taxonomy = taxonomy[:i]
+	}
+	lineage, name, isRanked := parseRankedTaxonomy(taxonomy, "d")
+	return isRanked && s.setLineage(id, lineage, name)
+}
+
+// parseGreengenes() parses "ID k__Bacteria; p__...; g__Genus; s__species".
+func parseGreengenes(header string, s *Species) bool {
+	id, taxonomy := splitHeader(header)
+	lineage, name, isRanked := parseRankedTaxonomy(taxonomy, "k")
+	return isRanked && s.setLineage(id, lineage, name)
+}
+
+// parseUNITE() parses "Name|Accession|SH|...|k__Fungi;p__...;s__Species".
+// The Id is the accession, or the first field if there are only two.
+func parseUNITE(header string, s *Species) bool {
+	fields := strings.Split(header, "|")
+	if len(fields) < 2 {
+		return false
+	}
+	id := fields[0]
+	if len(fields) > 2 {
+		id = fields[1]
+	}
+	lineage, name, isRanked := parseRankedTaxonomy(fields[len(fields)-1],
+		"k")
+	return isRanked && s.setLineage(id, lineage, name)
+}
+
+// parseRDP() parses the RDP classifier training sets,
+// "ID Root;Bacteria;...;Genus", and the RDP downloads,
+// "ID Description Lineage=Root;rootrank;Bacteria;domain;...;Genus;genus",
+// whose ranks below the genus and between the main ranks are skipped.
+func parseRDP(header string, s *Species) bool {
+	id, rest := splitHeader(header)
+	var lineage Lineage
+	name := ""
+	if i := strings.Index(rest, "Lineage="); i >= 0 {
+		name = strings.TrimSpace(strings.Split(rest[:i], ";")[0])
+		parts := strings.Split(strings.TrimSuffix(rest[i+len("Lineage="):],
+			";"), ";")
+		if len(parts)%2 != 0 || parts[0] != "Root" {
+			return false
+		}
+		for j := 0; j < len(parts); j += 2 {
+			for r, rankName := range rankNames {
+				if parts[j+1] == rankName {
+					lineage[r] = Class(parts[j])
+				}
+			}
+		}
+	} else if strings.HasPrefix(rest, "Root;") {
+		taxa := strings.Split(strings.TrimSuffix(rest, ";"), ";")[1:]
+		lineage = lineageOfTaxa(taxa)
+	} else {
+		return false
+	}
+	return s.setLineage(id, lineage, name)
+}
+
+// parseNCBI() parses "ID Genus species description", as in the NCBI 16S
+// RefSeq records. Only the genus and the species name are known.
+func parseNCBI(header string, s *Species) bool {
+	id, rest := splitHeader(header)
+	words := strings.Fields(rest)
+	if len(words) == 0 {
+		return false
+	}
+	if words[0] == "Candidatus" && len(words) > 1 {
+		words = append([]string{words[0] + " " + words[1]}, words[2:]...)
+	}
+	var lineage Lineage
+	lineage[RankGenus] = Class(strings.Trim(words[0], "[]"))
+	name := words[0]
+	if len(words) > 1 {
+		name += " " + words[1]
+	}
+	return s.setLineage(id, lineage, name)
+}
+
+var rankPrefixes = map[string]Rank{"d": RankDomain, "k": RankDomain,
+	"p": RankPhylum, "c": RankClass, "o": RankOrder, "f": RankFamily,
+	"g": RankGenus}
+
+// parseRankedTaxonomy() parses a taxonomy whose taxa are prefixed by their
+// rank, as "d__Bacteria;p__Firmicutes;...;s__Bacillus subtilis", and
+// returns its lineage and species name. The first taxon must be the domain,
+// with the given prefix; empty ranks are allowed.
+func parseRankedTaxonomy(taxonomy, domain string) (Lineage, string, bool) {
+	var lineage Lineage
+	name := ""
+	for i, part := range strings.Split(taxonomy, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" && i > 0 {
+			continue
+		}
+		prefix, taxon, isRanked := strings.Cut(part, "__")
+		if !isRanked || (i == 0) != (prefix == domain) {
+			return lineage, "", false
+		}
+		if prefix == "s" {
+			name = taxon
+			continue
+		}
+		r, isExist := rankPrefixes[prefix]
+		if !isExist {
+			return lineage, "", false
+		}
+		lineage[r] = Class(taxon)
+	}
+	return lineage, name, true
+}
+
+// setLineage() normalizes a lineage and stores it in s, with the Id and the
+// species name. The taxonomy is written as a SILVA taxonomy of every rank
+// and the species name, which ParseLineage() reads back to the same
+// lineage. It reports false if the lineage names no taxon.
+func (s *Species) setLineage(id string, lineage Lineage, name string) bool {
+	lineage = lineage.normalize()
+	if lineage[RankGenus] == "" {
+		return false
+	}
+	taxa := make([]string, 0, NumRanks+1)
+	for _, taxon := range lineage {
+		taxa = append(taxa, string(taxon))
+	}
+	s.Id = id
+	s.Taxonomy = strings.Join(append(taxa, name), ";")
+	s.Class = lineage[RankGenus]
+	s.Name = name
+	s.Lineage = lineage
+	return true
+}
+
+// normalize() names every empty rank below a named one after the deepest
+// named taxon above it, as mothur does (e.g. "Enterobacteriaceae_unclassified"
+// for a species whose genus is not known), so that every species has a
+// class. Ranks above the first named one stay empty.
+func (l Lineage) normalize() Lineage {
+	named := Class("")
+	for r, taxon := range l {
+		if taxon != "" {
+			named = taxon
+		} else if named != "" {
+			l[r] = named + "_unclassified"
+		}
+	}
+	return l
+}
@@ -0,0 +1,145 @@
+package classifier
+
+import (
+	"errors"
+	"testing"
+)
+
+var headerTests = []struct {
+	format	string
+	header	string
+	id		string
+	lineage	Lineage
+	name	string
+}{
+	{"silva", "AB1 Bacteria;Firmicutes;Bacilli;Bacillales;Bacillaceae;" +
+		"Bacillus;Bacillus subtilis", "AB1", Lineage{"Bacteria", "Firmicutes",
+		"Bacilli", "Bacillales", "Bacillaceae", "Bacillus"},
+		"Bacillus subtilis"},
+	{"silva", "AB2 Bacteria;Firmicutes;Bacillus;Bacillus sp.", "AB2",
+		Lineage{"Bacteria", "Firmicutes", "Firmicutes_unclassified",
+		"Firmicutes_unclassified", "Firmicutes_unclassified", "Bacillus"},
+		"Bacillus sp."},
+	{"gtdb", "RS_GCF_1.1 d__Bacteria;p__Firmicutes;c__Bacilli;" +
+		"o__Bacillales;f__Bacillaceae;g__Bacillus;s__Bacillus subtilis " +
+		"[locus_tag=1] [location=1..1550]", "RS_GCF_1.1", Lineage{"Bacteria",
+		"Firmicutes", "Bacilli", "Bacillales", "Bacillaceae", "Bacillus"},
+		"Bacillus subtilis"},
+	{"greengenes", "1111 k__Bacteria; p__Firmicutes; c__Bacilli; " +
+		"o__Bacillales; f__Bacillaceae; g__; s__", "1111", Lineage{"Bacteria",
+		"Firmicutes", "Bacilli", "Bacillales", "Bacillaceae",
+		"Bacillaceae_unclassified"}, ""},
+	{"unite", "Amanita_muscaria|KX1|SH1.08FU|reps|k__Fungi;p__Basidiomycota;" +
+		"c__Agaricomycetes;o__Agaricales;f__Amanitaceae;g__Amanita;" +
+		"s__Amanita_muscaria", "KX1", Lineage{"Fungi", "Basidiomycota",
+		"Agaricomycetes", "Agaricales", "Amanitaceae", "Amanita"},
+		"Amanita_muscaria"},
+	{"rdp", "S001 Root;Bacteria;Firmicutes;Bacilli;Bacillales;Bacillaceae;" +
+		"Bacillus", "S001", Lineage{"Bacteria", "Firmicutes", "Bacilli",
+		"Bacillales", "Bacillaceae", "Bacillus"}, ""},
+	{"rdp", "S002 Bacillus subtilis; str 168 Lineage=Root;rootrank;" +
+		"Bacteria;domain;Firmicutes;phylum;Bacilli;class;Bacillales;order;" +
+		"Bacillaceae 1;family;Bacillus;genus", "S002", Lineage{"Bacteria",
+		"Firmicutes", "Bacilli", "Bacillales", "Bacillaceae 1", "Bacillus"},
+		"Bacillus subtilis"},
+	{"ncbi", "NR_1.1 Bacillus subtilis strain 168 16S ribosomal RNA",
+		"NR_1.1", Lineage{RankGenus: "Bacillus"}, "Bacillus subtilis"},
+	{"ncbi", "NR_2.1 Candidatus Phytoplasma mali strain AT", "NR_2.1",
+		Lineage{RankGenus: "Candidatus Phytoplasma"},
+		"Candidatus Phytoplasma mali"},
+}
+
+func TestHeaderFormats(t *testing.T) {
+	for _, test := range headerTests {
+		t.Run(test.format+" "+test.id, func(t *testing.T) {
+			format, err := LookupHeaderFormat(test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s Species
+			if !format(test.header, &s) {
+				t.Fatalf("%q not parsed", test.header)
+			}
+			if s.Id != test.id || s.Lineage != test.lineage ||
+				s.Name != test.name || s.Class != test.lineage[RankGenus] {
+				t.Errorf("got %q %v %q, want %q %v %q", s.Id, s.Lineage,
+					s.Name, test.id, test.lineage, test.name)
+			}
+			// The taxonomy is written as a SILVA taxonomy, which reads
+			// back to the same species.
+			var again Species
+			if !parseSILVA(s.Id+" "+s.Taxonomy, &again) ||
+				again.Lineage != s.Lineage || again.Name != s.Name ||
+				again.Taxonomy != s.Taxonomy {
+				t.Errorf("taxonomy %q reads back as %v %q", s.Taxonomy,
+					again.Lineage, again.Name)
+			}
+		})
+	}
+}
+
+func TestAutoFormat(t *testing.T) {
+	for _, test := range headerTests {
+		if test.format == "ncbi" {
+			continue
+		}
+		t.Run(test.format+" "+test.id, func(t *testing.T) {
+			var s Species
+			if !AutoFormat(test.header, &s) {
+				t.Fatalf("%q not parsed", test.header)
+			}
+			if s.Id != test.id || s.Lineage != test.lineage ||
+				s.Name != test.name {
+				t.Errorf("got %q %v %q, want %q %v %q", s.Id, s.Lineage,
+					s.Name, test.id, test.lineage, test.name)
+			}
+		})
+	}
+}
+
+func TestHeaderFormatsReject(t *testing.T) {
+	tests := []struct {
+		format	string
+		header	string
+	}{
+		{"silva", "AB1"},
+		{"silva", "AB1 Bacteria"},
+		{"gtdb", "AB1 Bacteria;Firmicutes;Bacillus;Bacillus sp."},
+		{"gtdb", "AB1 k__Bacteria;g__Bacillus"},
+		{"greengenes", "1111 d__Bacteria;g__Bacillus"},
+		{"greengenes", "1111 k__Bacteria;x__Bacillus"},
+		{"greengenes", "1111 k__;p__;c__;o__;f__;g__;s__"},
+		{"unite", "KX1 k__Fungi;g__Amanita"},
+		{"rdp", "S001 Bacteria;Firmicutes;Bacillus"},
+		{"rdp", "S002 Lineage=Root;rootrank;Bacteria"},
+		{"ncbi", "NR_1.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			format, err := LookupHeaderFormat(test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := Species{Id: "untouched"}
+			if format(test.header, &s) {
+				t.Errorf("%q parsed as %v", test.header, s.Lineage)
+			}
+			if s.Id != "untouched" {
+				t.Errorf("%q changed the species", test.header)
+			}
+		})
+	}
+}
+
+func TestLookupHeaderFormat(t *testing.T) {
+	for _, name := range HeaderFormats() {
+		if format, err := LookupHeaderFormat(name); err != nil ||
+			format == nil {
+			t.Errorf("LookupHeaderFormat(%q) = %v", name, err)
+		}
+	}
+	if _, err := LookupHeaderFormat("embl"); !errors.Is(err,
+		ErrUnknownFormat) {
+		t.Errorf("got %v, want ErrUnknownFormat", err)
+	}
+}
@@ -1,39 +1,137 @@
-package main 
+package classifier
 
 import (
-	"log"
-	"fmt"
-	"os"
 	"io"
 	"encoding/gob"
 	"container/heap"
-	"math/rand"
+	"math"
 	"sort"
-	"time"
 )
 
-var kNNDataBase string = "kNNClassifier.gob"
+// The file the command line tool stores the kNN classifier in.
+const DefaultKNNModel = "kNNClassifier.gob"
 
+// The number of neighbours Predict() uses unless SetK() is called.
+const DefaultNeighbours = 5
+
+func init() {
+	Register("KNN", Backend{
+		New: func(cfg KmerConfig) (Classifier, error) {
+			return NewKNNClassifier(cfg)
+		},
+		Model: DefaultKNNModel,
+	})
+}
+
+// The inverted index of the kNN classifier: every species is stored once in
+// species, and each word maps to the ids (positions in species) of the 
+// species containing it, and to the number of times it occurs in each of 
+// them (in counts, in the same order).
 type KNNClassifier struct {
 	Classes			[]Class
-	data			map[string][]*Species
+	species			[]*Species
+	data			*postingLists
+	counts			*postingLists
+	// The number of distinct words of every species, and the norm of its 
+	// word counts, by id.
+	sizes			[]int32
+	norms			[]float64
+	config			KmerConfig
+	metric			Similarity
+	voting			VotingScheme
+	hierarchical	bool
 	learned			int
 	seen 			int
+	// The number of neighbours used by Predict().
+	k				int
 }
 
+// SerializedKNNClassifier is the on-disk form of the inverted index. Species
+// are stored without their sequences and words, and the postings of all 
+// words are packed in one slice: the postings of Words[i] are 
+// Postings[Offsets[i]:Offsets[i+1]]. Counts holds the word counts of the 
+// postings; it is empty for classifiers stored before counts were kept, 
+// whose words all count once.
 type SerializedKNNClassifier struct {
 	Classes 		[]Class
-	Data 			map[string][]*Species
+	Species			[]IndexedSpecies
+	Words 			[]Word
+	Offsets			[]int32
+	Postings		[]int32
+	Counts			[]int32
+	Config			KmerConfig
+	Metric			Similarity
+	Voting			VotingScheme
+	Hierarchical	bool
 	Learned 		int
 	Seen 			int
 }
 
+type IndexedSpecies struct {
+	Id			string
+	Taxonomy	string
+	Name 		string
+	Class 		Class
+}
+
+// postingLists maps each word to the ids of the species containing it. For 
+// small k the lists are kept in a dense array indexed by the word itself,
+// otherwise in a map.
+type postingLists struct {
+	dense		[][]int32
+	sparse		map[Word][]int32
+}
+
+func newPostingLists(cfg KmerConfig) *postingLists {
+	if cfg.isDense() {
+		return &postingLists{dense: make([][]int32, cfg.NumWords())}
+	}
+	return &postingLists{sparse: make(map[Word][]int32)}
+}
+
+func (pl *postingLists) get(word Word) []int32 {
+	if pl.dense != nil {
+		return pl.dense[word]
+	}
+	return pl.sparse[word]
+}
+
+func (pl *postingLists) set(word Word, ids []int32) {
+	if pl.dense != nil {
+		pl.dense[word] = ids
+	} else {
+		pl.sparse[word] = ids
+	}
+}
+
+func (pl *postingLists) add(word Word, id int32) {
+	pl.set(word, append(pl.get(word), id))
+}
+
+// words() returns the words having a posting list, sorted.
+func (pl *postingLists) words() []Word {
+	words := make([]Word, 0)
+	if pl.dense != nil {
+		for word, ids := range pl.dense {
+			if len(ids) > 0 {
+				words = append(words, Word(word))
+			}
+		}
+		return words
+	}
+	for word := range pl.sparse {
+		words = append(words, word)
+	}
+	return uniqueWords(words)
+}
+
 
 
 // An Item is something we manage in a priority queue.
 type Item struct {
 	value    *Species // The value of the item; a pointer to a Species.
-	priority int    // The priority of the item in the queue.
+	priority float64    // The priority of the item in the queue.
+	position int32      // The learning position, for species of equal IDs.
 	// The index is needed by update and is maintained by the heap.Interface 
 	//methods.
 	index int // The index of the item in the heap.
@@ -45,9 +143,21 @@ type PriorityQueue []*Item
 func (pq PriorityQueue) Len() int { return len(pq) }
 
 func (pq PriorityQueue) Less(i, j int) bool {
-	// We want Pop to give us the highest, not lowest, priority so we use 
-	//greater than here.
-	return pq[i].priority < pq[j].priority
+	// The farthest species is at the top of the heap, to be replaced first.
+	return farther(pq[i], pq[j])
+}
+
+// farther() tells if an item ranks after another, the reverse of nearer():
+// the less similar, then the one with the larger reference ID, and then the
+// one learned last, so that the search is deterministic.
+func farther(a, b *Item) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	if a.value.Id != b.value.Id {
+		return a.value.Id > b.value.Id
+	}
+	return a.position > b.position
 }
 
 func (pq PriorityQueue) Swap(i, j int) {
@@ -72,238 +182,580 @@ func (pq *PriorityQueue) Pop() interface{} {
 	return item
 }
 
-func KNNLearnData(d RawData) *KNNClassifier {
-	numOfClasses := len(d.classes)
+// NewKNNClassifier() returns an empty kNN classifier using the given k-mer
+// settings.
+func NewKNNClassifier(cfg KmerConfig) (*KNNClassifier, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
 	kc := &KNNClassifier{
-		make([]Class, numOfClasses),
-		make(map[string][]*Species),
-		0,
-		0,
+		Classes:	make([]Class, 0),
+		species:	make([]*Species, 0),
+		data:		newPostingLists(cfg),
+		counts:		newPostingLists(cfg),
+		sizes:		make([]int32, 0),
+		norms:		make([]float64, 0),
+		config:		cfg,
+		k:			DefaultNeighbours,
+	}
+	return kc, nil
+}
+
+func KNNLearnData(d *RawData) (*KNNClassifier, error) {
+	kc, err := NewKNNClassifier(d.config)
+	if err != nil {
+		return nil, err
+	}
+	if err := kc.Learn(d); err != nil {
+		return nil, err
 	}
-	copy(kc.Classes, d.classes)
-	kc.LearnDataHelper(d)
-	//kc.WritekNNToFile()
 	//fmt.Println("Number of classes kNN Classifier learned:", len(kc.Classes))
 	//fmt.Println("Number of species kNN Classifier learned:", kc.learned)
-	return kc
+	return kc, nil
 }
 
-func (kc *KNNClassifier) LearnDataHelper(d RawData) {
+// Learn() adds the species of a data set to the index. The words of the 
+// data must have been generated with the settings of the classifier.
+func (kc *KNNClassifier) Learn(d *RawData) error {
+	if d.config != kc.config {
+		return ErrConfigMismatch
+	}
+	isKnown := make(map[Class]bool)
+	for _, class := range kc.Classes {
+		isKnown[class] = true
+	}
+	for _, class := range d.classes {
+		if !isKnown[class] {
+			kc.Classes = append(kc.Classes, class)
+		}
+	}
+	kc.LearnDataHelper(d)
+	return nil
+}
+
+func (kc *KNNClassifier) LearnDataHelper(d *RawData) {
 	numOfSpecies := len(d.species)
 	for i := 0; i < numOfSpecies; i++ {
-		numOfWords := len(d.species[i].Words) 
+		f := d.species[i].Features()
+		numOfWords := len(f.Words) 
+		id := int32(len(kc.species))
+		kc.species = append(kc.species, d.species[i])
 		kc.learned++
+		norm := 0.0
 		for j := 0; j < numOfWords; j++ {
-			word := d.species[i].Words[j]
-			_, isExist := kc.data[word]
-			if !isExist {
-				kc.data[word] = make([]*Species, 0)
-			}
-			kc.data[word] = append(kc.data[word], d.species[i])
+			word := f.Words[j]
+			kc.data.add(word, id)
+			kc.counts.add(word, f.Count(j))
+			norm += float64(f.Count(j)) * float64(f.Count(j))
 		}
+		kc.sizes = append(kc.sizes, int32(numOfWords))
+		kc.norms = append(kc.norms, math.Sqrt(norm))
 	}
 }
 
-func (kc *KNNClassifier) WritekNNToFile() {
-	file, err := os.Create("kNNClassifier.gob")
+func (kc *KNNClassifier) WritekNNToFile(fileName string) error {
+	return createFile(fileName, func(file io.Writer) error {
+		_, err := kc.WriteTo(file)
+		return err
+	})
+}
+
+// WriteTo() gob-encodes the inverted index.
+func (kc *KNNClassifier) WriteTo(file io.Writer) (int64, error) {
+	cw := &countingWriter{w: file}
+	enc := gob.NewEncoder(cw)
+	err := enc.Encode(kc.serialize())
+	return cw.n, err
+}
+
+// Load an existing kNN classifier from file. A missing file is reported as
+// ErrModelNotFound.
+func LoadKCFromFile(fileName string) (*KNNClassifier, error) {
+	file, err := openModel(fileName)
 	if err != nil {
-		log.Fatal("Error: There was a problem when creating kNN" +
-			" Classifier file!")
+		return nil, err
 	}
 	defer file.Close()
-	kc.WriteTo(file)
+	return ReadKNNClassifier(file)
 }
 
-func (kc *KNNClassifier) WriteTo(file io.Writer) {
-	enc := gob.NewEncoder(file)
-	err := enc.Encode(&SerializedKNNClassifier{kc.Classes, kc.data, 
-		kc.learned, kc.seen})
+// ReadKNNClassifier() decodes an index written by WriteTo(). An index that 
+// has not learned any species is reported as ErrUntrainedModel.
+func ReadKNNClassifier(file io.Reader) (*KNNClassifier, error) {
+	kc := &KNNClassifier{k: DefaultNeighbours}
+	if _, err := kc.ReadFrom(file); err != nil {
+		return nil, err
+	}
+	return kc, nil
+}
+
+// ReadFrom() replaces the index by one written by WriteTo(). The number of
+// neighbours is kept.
+func (kc *KNNClassifier) ReadFrom(file io.Reader) (int64, error) {
+	cr := &countingReader{r: file}
+	dec := gob.NewDecoder(cr)
+	skc := new(SerializedKNNClassifier)
+	err := dec.Decode(skc)
 	if err != nil {
-		log.Fatal("Error: There was a problem when encoding kNN" +
-			" Classifier data!", err)
+		return cr.n, err
+	}
+	if skc.Learned == 0 {
+		return cr.n, ErrUntrainedModel
 	}
-	fmt.Println("Write kNN classifier data file successfully!")
+	if err := skc.Config.Check(); err != nil {
+		return cr.n, err
+	}
+	k := kc.k
+	*kc = *skc.deserialize()
+	kc.k = k
+	return cr.n, nil
 }
 
+// Config() returns the k-mer settings the classifier was trained with.
+func (kc *KNNClassifier) Config() KmerConfig {
+	return kc.config
+}
 
-func LoadKCFromFile() *KNNClassifier {
-	file, err := os.Open(kNNDataBase)
-	if err != nil {
-		log.Fatal("Error: There was a problem when loading kNN" +
-			" classifier data from local file!")
+// Learned() returns the number of species in the index.
+func (kc *KNNClassifier) Learned() int {
+	return kc.learned
+}
+
+// Name() returns "KNN", the name of the backend.
+func (kc *KNNClassifier) Name() string {
+	return "KNN"
+}
+
+// SetK() sets the number of neighbours used by Predict().
+func (kc *KNNClassifier) SetK(k int) error {
+	if k < 1 {
+		return ErrInvalidK
 	}
-	dec := gob.NewDecoder(file)
-	skc := new(SerializedKNNClassifier)
-	err = dec.Decode(skc)
-	if err != nil {
-		log.Fatal("Error: There was a problem when decoding local" +
-			"kNN classifier data!", err)
+	kc.k = k
+	return nil
+}
+
+// SetMetric() sets the similarity the neighbours are ranked by. It is 
+// stored with the classifier.
+func (kc *KNNClassifier) SetMetric(metric Similarity) {
+	kc.metric = metric
+}
+
+// Metric() returns the similarity the neighbours are ranked by.
+func (kc *KNNClassifier) Metric() Similarity {
+	return kc.metric
+}
+
+// SetVoting() sets how the votes of the neighbours are weighed. It is 
+// stored with the classifier.
+func (kc *KNNClassifier) SetVoting(voting VotingScheme) {
+	kc.voting = voting
+}
+
+// Voting() returns how the votes of the neighbours are weighed.
+func (kc *KNNClassifier) Voting() VotingScheme {
+	return kc.voting
+}
+
+// SetHierarchical() sets whether the lineage is voted rank by rank from the
+// domain down, see KNNPredictLineage(). It is stored with the classifier.
+func (kc *KNNClassifier) SetHierarchical(hierarchical bool) {
+	kc.hierarchical = hierarchical
+}
+
+// Hierarchical() tells if the lineage is voted rank by rank.
+func (kc *KNNClassifier) Hierarchical() bool {
+	return kc.hierarchical
+}
+
+// Predict() predicts a sequence from the number of neighbours set by SetK().
+func (kc *KNNClassifier) Predict(f Features) (Prediction, error) {
+	return kc.predict(f, kc.k)
+}
+
+// serialize() packs the inverted index into its on-disk form. Words are 
+// sorted so that the same classifier is always written identically.
+func (kc *KNNClassifier) serialize() *SerializedKNNClassifier {
+	skc := &SerializedKNNClassifier{
+		Classes:	kc.Classes,
+		Species:	make([]IndexedSpecies, len(kc.species)),
+		Words:		kc.data.words(),
+		Config:		kc.config,
+		Metric:		kc.metric,
+		Voting:		kc.voting,
+		Hierarchical:	kc.hierarchical,
+		Learned:	kc.learned,
+		Seen:		kc.seen,
+	}
+	for i, spe := range kc.species {
+		skc.Species[i] = IndexedSpecies{spe.Id, spe.Taxonomy, spe.Name, 
+			spe.Class}
 	}
-	return &KNNClassifier{skc.Classes, skc.Data, skc.Learned, skc.Seen}
+	numOfPostings := 0
+	for _, word := range skc.Words {
+		numOfPostings += len(kc.data.get(word))
+	}
+	skc.Offsets = make([]int32, 0, len(skc.Words)+1)
+	skc.Postings = make([]int32, 0, numOfPostings)
+	skc.Counts = make([]int32, 0, numOfPostings)
+	for _, word := range skc.Words {
+		skc.Offsets = append(skc.Offsets, int32(len(skc.Postings)))
+		skc.Postings = append(skc.Postings, kc.data.get(word)...)
+		skc.Counts = append(skc.Counts, kc.counts.get(word)...)
+	}
+	skc.Offsets = append(skc.Offsets, int32(len(skc.Postings)))
+	return skc
 }
 
-func (kc *KNNClassifier) KNNPredict(words []string, k int) Class {
-	speciesFreq := make(map[*Species]int)
-	for _, word := range words {
-		length := len(kc.data[word])
-		temp_data := kc.data[word]
-		for i := 0; i < length; i++  {
-			temp_species := temp_data[i]
-			speciesFreq[temp_species]++
+// deserialize() rebuilds the inverted index. The postings of every word
+// share the backing array of skc.Postings, so no copy is made. The sizes and
+// norms of the species are computed again from the postings.
+func (skc *SerializedKNNClassifier) deserialize() *KNNClassifier {
+	kc := &KNNClassifier{
+		Classes:	skc.Classes,
+		species:	make([]*Species, len(skc.Species)),
+		data:		newPostingLists(skc.Config),
+		counts:		newPostingLists(skc.Config),
+		sizes:		make([]int32, len(skc.Species)),
+		norms:		make([]float64, len(skc.Species)),
+		config:		skc.Config,
+		metric:		skc.Metric,
+		voting:		skc.Voting,
+		hierarchical:	skc.Hierarchical,
+		learned:	skc.Learned,
+		seen:		skc.Seen,
+		k:			DefaultNeighbours,
+	}
+	for i, spe := range skc.Species {
+		kc.species[i] = &Species{
+			Id:			spe.Id,
+			Taxonomy:	spe.Taxonomy,
+			Name:		spe.Name,
+			Class:		spe.Class,
+			Lineage:	ParseLineage(spe.Taxonomy),
 		}
 	}
+	counts := skc.Counts
+	if len(counts) != len(skc.Postings) {
+		// Every word counts once, and the counts are kept so that more 
+		// species can be learned.
+		counts = make([]int32, len(skc.Postings))
+		for j := range counts {
+			counts[j] = 1
+		}
+	}
+	for i, word := range skc.Words {
+		start, end := skc.Offsets[i], skc.Offsets[i+1]
+		kc.data.set(word, skc.Postings[start:end:end])
+		kc.counts.set(word, counts[start:end:end])
+		for j := start; j < end; j++ {
+			id, count := skc.Postings[j], counts[j]
+			kc.sizes[id]++
+			kc.norms[id] += float64(count) * float64(count)
+		}
+	}
+	for id := range kc.norms {
+		kc.norms[id] = math.Sqrt(kc.norms[id])
+	}
+	return kc
+}
 
+func (kc *KNNClassifier) KNNPredict(words []Word, k int) (Class, error) {
+	p, err := kc.KNNPredictLineage(words, k)
+	return p.Class, err
+}
+
+// KNNPredictLineage() predicts the lineage of a sequence from its k nearest
+// species. The class with the most votes is predicted (see VotingScheme and
+// elect() for ties), with the lineage of its nearest species, and the 
+// confidence of every rank is the share of the votes of the k neighbours 
+// going to species of the same taxon. Under SetHierarchical(true), each rank
+// instead takes the taxon with the most votes among the neighbours that 
+// agree with the ranks above, going down from the domain, so that a class
+// outvoted by the other classes of its family may still win. When fewer 
+// than k species share words with the sequence, only those vote, and under
+// uniform voting the missing neighbours lower the confidence; when none 
+// does, the class is NoHit. A k below one is reported as ErrInvalidK.
+func (kc *KNNClassifier) KNNPredictLineage(words []Word, 
+	k int) (Prediction, error) {
+	return kc.predict(Features{Words: words}, k)
+}
 
-	kMax := FindKMax(speciesFreq, k)
-	//for spe, num := range speciesFreq {
-	//	fmt.Println(spe.name, num)
-	//}
-	classMap := make(map[Class]int)
-	for i := 0; i < k; i++ {
-		classMap[kMax[i].Class]++
+func (kc *KNNClassifier) predict(f Features, k int) (Prediction, error) {
+	kMax, hits, err := kc.nearest(f, k)
+	if err != nil {
+		return Prediction{}, err
 	}
+	return kc.vote(kMax, hits, k), nil
+}
 
-	countClass := 0
-	var maxClass Class 
-	for class, num := range classMap {
-		if num > countClass {
-			maxClass = class 
-		}
+// Nearest() returns the k species of the index nearest to a sequence, the
+// nearest first, ranked by the similarity of the classifier and then by 
+// reference ID. Only the species sharing words with the sequence are 
+// returned, so there may be fewer than k, or none. A k below one is 
+// reported as ErrInvalidK.
+func (kc *KNNClassifier) Nearest(f Features, k int) ([]Neighbour, error) {
+	kMax, hits, err := kc.nearest(f, k)
+	if err != nil {
+		return nil, err
 	}
+	neighbours := make([]Neighbour, len(kMax))
+	for i, spe := range kMax {
+		neighbours[i] = newNeighbour(spe, hits[spe])
+	}
+	return neighbours, nil
+}
 
-	return maxClass
+// nearest() searches the index for a sequence, and returns its k nearest 
+// species sorted as by nearer(), together with all the hits of the search.
+func (kc *KNNClassifier) nearest(f Features, k int) ([]*Species, 
+	map[*Species]*hit, error) {
+	if k < 1 {
+		return nil, nil, ErrInvalidK
+	}
+	if kc.learned == 0 {
+		return nil, nil, ErrUntrainedModel
+	}
+	hits := kc.search(f, nil)
+	items := make([]Item, 0, len(hits))
+	for spe, h := range hits {
+		items = append(items, Item{value: spe, priority: h.similarity, 
+			position: h.id})
+	}
+	kMax := findKMax(items, k)
+	sort.Slice(kMax, func(i, j int) bool {
+		return nearer(kMax[i], kMax[j], hits)
+	})
+	return kMax, hits, nil
 }
 
-func FindKMax(s map[*Species]int, k int) []*Species {
-	pq := make(PriorityQueue, 1)
-	isFirst := true
-	for value, priority := range s {
-		if isFirst {
-			pq[0] = &Item{
-			value:		value,
-			priority: 	priority,
-			index:		0, 
+func newNeighbour(spe *Species, h *hit) Neighbour {
+	return Neighbour{spe.Id, spe.Taxonomy, spe.Class, h.shared, 
+		h.similarity, h.distance}
+}
+
+// A hit is a species of the index sharing words with a query.
+type hit struct {
+	id				int32
+	shared			int
+	dot				float64
+	similarity		float64
+	distance		float64
+}
+
+// search() finds the species of the index sharing words with a query, and 
+// scores them with the similarity of the classifier. The species for which
+// exclude(id) is true are left out; exclude may be nil.
+func (kc *KNNClassifier) search(f Features, 
+	exclude func(id int) bool) map[*Species]*hit {
+	hits := make(map[*Species]*hit)
+	queryNorm := 0.0
+	for i, word := range f.Words {
+		queryCount := float64(f.Count(i))
+		queryNorm += queryCount * queryCount
+		temp_data := kc.data.get(word)
+		counts := kc.counts.get(word)
+		length := len(temp_data)
+		for j := 0; j < length; j++  {
+			if exclude != nil && exclude(int(temp_data[j])) {
+				continue
 			}
-			heap.Init(&pq)
-			isFirst = false
-		} else {
-			item := &Item{
-				value:		value,
-				priority:	priority,
+			temp_species := kc.species[temp_data[j]]
+			h, isExist := hits[temp_species]
+			if !isExist {
+				h = &hit{id: temp_data[j]}
+				hits[temp_species] = h
 			}
-			if len(pq) < k {
-				heap.Push(&pq, item)
-			} else if len(pq) == k {
-				oldItem := heap.Pop(&pq).(*Item)
-				//fmt.Println(oldItem.value.class)
-				if oldItem.priority > item.priority {
-					heap.Push(&pq, oldItem)
-				} else {
-					heap.Push(&pq, item)
-				}
+			h.shared++
+			if counts != nil {
+				h.dot += queryCount * float64(counts[j])
 			} else {
-				log.Fatal("Error: There was an error when finding closest" +
-					" k species!")
+				h.dot += queryCount
 			}
 		}
 	}
-	result := make([]*Species, k)
-	for i := 0; i < k; i++ {
-		result[i] = pq[i].value
-		//fmt.Println(pq[i].value.name, pq[i].value.class)
+	queryNorm = math.Sqrt(queryNorm)
+	for _, h := range hits {
+		mt := match{h.shared, h.dot, len(f.Words), int(kc.sizes[h.id]), 
+			queryNorm, kc.norms[h.id]}
+		h.similarity = kc.metric.score(mt, kc.config.K)
+		h.distance = kc.metric.distance(mt, h.similarity)
 	}
-	return result
+	return hits
 }
 
-func CrossValidation(d RawData) int {
-	rand.Seed(time.Now().UTC().UnixNano())
-	correctRate := make(map[int]float64)
-	for k := 1; k <= 10; k++ {
-		temp := make([]float64, 20)
-		average := 0.0
-		for i := 0; i < 20; i++ {
-			fmt.Println("Repeat times:", i+1)
-			temp[i] = CrossValidationHelper(d, k)
-			average += temp[i]
+// vote() predicts a lineage from the nearest species found for k 
+// neighbours, see KNNPredictLineage(). The votes for the class of every 
+// neighbour are returned with the prediction. A sequence sharing no word 
+// with the index is predicted as NoHit.
+func (kc *KNNClassifier) vote(candidates []*Species, hits map[*Species]*hit,
+	k int) Prediction {
+	if len(candidates) == 0 {
+		return Prediction{Class: NoHit, Neighbours: []Neighbour{}, 
+			Votes: []Vote{}}
+	}
+	candidates = append([]*Species(nil), candidates...)
+	sort.Slice(candidates, func(i, j int) bool {
+		return nearer(candidates[i], candidates[j], hits)
+	})
+	p := Prediction{Neighbours: make([]Neighbour, 0, len(candidates))}
+	total := float64(k)
+	if kc.voting != UniformVote {
+		total = 0
+	}
+	for _, spe := range candidates {
+		h := hits[spe]
+		if h.similarity > p.Score {
+			p.Score = h.similarity
 		}
-		average /= 20
-		correctRate[k] = average
-	}
-	cr := -1.0
-	optimalK := 1
-	for k, rate := range correctRate {
-		//fmt.Println("k =", k, "   average correct rate:", rate)
-		if rate > cr {
-			optimalK = k
-			cr = rate
+		if kc.voting != UniformVote {
+			total += kc.voting.weight(h)
 		}
+		p.Neighbours = append(p.Neighbours, newNeighbour(spe, h))
+	}
+	votes := kc.voting.elect(candidates, hits, func(spe *Species) Class {
+		return spe.Class
+	})
+	p.Votes = make([]Vote, len(votes))
+	for i, t := range votes {
+		p.Votes[i] = t.Vote
 	}
-	fmt.Println("Optimal k =", optimalK, "average correct rate:", cr)
-	return optimalK
-}
-
-func CrossValidationHelper(d RawData, k int) float64 {
-	n := len(d.species)
-	//for _, spe := range d.species {
-	//	fmt.Println("RawData:", spe.name)
-	//}
-	var numOfTest int = n / 1000
-	//var numOfTrain int = n - numOfTest
-	testSpecies := make([]*Species, numOfTest)
-	rand := GenerateRandomNum(n, numOfTest)
-	for i := 0; i < numOfTest; i++ {
-		testSpecies[i] = d.species[rand[i]]
-	}
-	sort.Ints(rand)
-
-	trainSpecies := make([]*Species, n)
-	copy(trainSpecies, d.species) 
-	for i := numOfTest - 1; i >= 0; i-- {
-		if rand[i] != n-1 {
-			trainSpecies = append(trainSpecies[:rand[i]], 
-				trainSpecies[rand[i]+1:]...)
-		} else {
-			trainSpecies = trainSpecies[:rand[i]]
+	if kc.hierarchical {
+		kc.voteDown(&p, candidates, hits, total)
+	} else {
+		p.Class = votes[0].Class
+		var nearest *Species
+		for _, spe := range candidates {
+			if spe.Class == p.Class {
+				nearest = spe
+				break
+			}
+		}
+		p.Lineage = nearest.Lineage
+		for _, spe := range candidates {
+			weight := kc.voting.weight(hits[spe])
+			for rank := 0; rank < NumRanks; rank++ {
+				if p.Lineage[rank] != "" && 
+					spe.Lineage[rank] == p.Lineage[rank] {
+					p.RankConfidence[rank] += weight
+				}
+			}
+		}
+		for rank := range p.RankConfidence {
+			if total > 0 {
+				p.RankConfidence[rank] /= total
+			}
 		}
 	}
-	/*
-	for _, spe := range testSpecies {
-		fmt.Println("test species: ", spe.name)
-	}
-	for _, spe := range trainSpecies {
-		fmt.Println("train species: ", spe.name)
-	}
-	*/
-	temp_trainData := RawData{
-		classes:	make([]Class, 0),
-		species:	trainSpecies,
-		classMap:	make(map[Class]int),
-	}
-	kc := KNNLearnData(temp_trainData)
-	count := 0
-	for i := 0; i < numOfTest; i++ {
-		//fmt.Println("testspecies:", testSpecies[i].name, testSpecies[i].class, 
-		//		"predict result:", kc.KNNPredict(testSpecies[i].words, k))
-		if testSpecies[i].Class == kc.KNNPredict(testSpecies[i].Words, k) {
-			count++
+	p.Confidence = p.RankConfidence[RankGenus]
+	return p
+}
+
+// voteDown() predicts the lineage of the hierarchical vote: going down from
+// the domain, each rank takes the taxon with the most votes among the 
+// neighbours that agree with the ranks above, and the class is elected 
+// among the neighbours left.
+func (kc *KNNClassifier) voteDown(p *Prediction, candidates []*Species, 
+	hits map[*Species]*hit, total float64) {
+	for rank := 0; rank < NumRanks; rank++ {
+		tallies := kc.voting.elect(candidates, hits, 
+			func(spe *Species) Class {
+				return spe.Lineage[rank]
+			})
+		if len(tallies) == 0 {
+			break
+		}
+		taxon := tallies[0].Class
+		p.Lineage[rank] = taxon
+		if total > 0 {
+			p.RankConfidence[rank] = tallies[0].Weight / total
+		}
+		agreeing := make([]*Species, 0, tallies[0].Count)
+		for _, spe := range candidates {
+			if spe.Lineage[rank] == taxon {
+				agreeing = append(agreeing, spe)
+			}
 		}
+		candidates = agreeing
+	}
+	if tallies := kc.voting.elect(candidates, hits, 
+		func(spe *Species) Class {
+			return spe.Class
+		}); len(tallies) > 0 {
+		p.Class = tallies[0].Class
 	}
-	fmt.Println("k:", k, "   correct rate:", 
-		float64(count) / float64(numOfTest))
-	return float64(count) / float64(numOfTest)
 }
 
-func GenerateRandomNum(n, m int) []int {
-	temp := make(map[int]int)
-	for len(temp) < m {
-		num := rand.Intn(n)
-		temp[num]++
+// PredictNeighbours() predicts a sequence once for every k of ks, leaving 
+// out the species of the index for which exclude(id) is true. Ids are the 
+// positions of the species in the order they were learned. The index is 
+// searched only once, and the species are ranked as by FindKMax(), so that
+// leave-one-out evaluation needs neither a new index per fold nor a new 
+// search per k.
+func (kc *KNNClassifier) PredictNeighbours(f Features, ks []int, 
+	exclude func(id int) bool) ([]Prediction, error) {
+	if kc.learned == 0 {
+		return nil, ErrUntrainedModel
+	}
+	for _, k := range ks {
+		if k < 1 {
+			return nil, ErrInvalidK
+		}
 	}
-	result := make([]int, m)
-	i := 0
-	for num, _ := range temp {
-		result[i] = num
-		i++
+	hits := kc.search(f, exclude)
+	nearest := make([]*Species, 0, len(hits))
+	for spe := range hits {
+		nearest = append(nearest, spe)
+	}
+	sort.Slice(nearest, func(i, j int) bool {
+		return nearer(nearest[i], nearest[j], hits)
+	})
+	predictions := make([]Prediction, len(ks))
+	for i, k := range ks {
+		candidates := nearest
+		if len(candidates) > k {
+			candidates = candidates[:k]
+		}
+		predictions[i] = kc.vote(candidates, hits, k)
+	}
+	return predictions, nil
+}
+
+// FindKMax() returns the k species with the highest scores, the highest 
+// first; ties go to the smaller reference ID. When s holds fewer than k 
+// species all of them are returned, and none when it is empty. A k below 
+// one is reported as ErrInvalidK.
+func FindKMax(s map[*Species]float64, k int) ([]*Species, error) {
+	if k < 1 {
+		return nil, ErrInvalidK
+	}
+	items := make([]Item, 0, len(s))
+	for value, priority := range s {
+		items = append(items, Item{value: value, priority: priority})
+	}
+	return findKMax(items, k), nil
+}
+
+// findKMax() returns the species of the k nearest items, the nearest first,
+// see farther().
+func findKMax(items []Item, k int) []*Species {
+	pq := make(PriorityQueue, 0, k)
+	for i := range items {
+		item := &items[i]
+		if len(pq) < k {
+			heap.Push(&pq, item)
+			continue
+		}
+		// The farthest species kept is replaced if the new one is nearer.
+		if farther(pq[0], item) {
+			item.index = 0
+			pq[0] = item
+			heap.Fix(&pq, 0)
+		}
+	}
+	result := make([]*Species, len(pq))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(&pq).(*Item).value
 	}
 	return result
-}
\ No newline at end of file
+}
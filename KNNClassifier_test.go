@@ -0,0 +1,297 @@
+package classifier
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// mutate() replaces a fraction of the bases of a sequence by random ones.
+func mutate(r *rand.Rand, sequence string, rate float64) string {
+	bases := []byte(sequence)
+	for i := range bases {
+		if r.Float64() < rate {
+			bases[i] = "ACGT"[r.Intn(4)]
+		}
+	}
+	return string(bases)
+}
+
+// newTestSpecies() returns a species of a genus of the test family, with
+// its lineage and its words and their counts generated with cfg.
+func newTestSpecies(id string, genus Class, sequence string,
+	cfg KmerConfig) Species {
+	name := string(genus) + " sp."
+	taxonomy := "Bacteria;Firmicutes;Bacilli;Bacillales;Bacillaceae;" +
+		string(genus) + ";" + name
+	s := Species{
+		Id:			id,
+		Taxonomy:	taxonomy,
+		Sequence:	sequence,
+		Name:		name,
+		Class:		genus,
+		Lineage:	ParseLineage(taxonomy),
+	}
+	f := GenerateFeatures(sequence, cfg)
+	s.Words, s.Counts = f.Words, f.Counts
+	return s
+}
+
+// unbalancedData() returns a data set of 12 genera, half of them with 5
+// species and half with 20, all mutated from a random sequence of their
+// own, and 2 more species of every genus to predict.
+func unbalancedData(cfg KmerConfig) (*RawData, []Species) {
+	r := rand.New(rand.NewSource(1))
+	species := make([]Species, 0)
+	queries := make([]Species, 0)
+	for g := 0; g < 12; g++ {
+		genus := Class(fmt.Sprintf("Genus%02d", g))
+		root := randomSequence(r, 600)
+		size := 5
+		if g%2 == 0 {
+			size = 20
+		}
+		for i := 0; i < size+2; i++ {
+			s := newTestSpecies(fmt.Sprintf("%s.%d", genus, i), genus,
+				mutate(r, root, 0.05), cfg)
+			if i < size {
+				species = append(species, s)
+			} else {
+				queries = append(queries, s)
+			}
+		}
+	}
+	return NewRawData(&species, cfg), queries
+}
+
+// The index must predict the same once written and read back, with the
+// metric and the voting it was saved with, and the same index must always
+// be written identically.
+func TestKNNClassifierSaveLoad(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	tests := []struct {
+		metric			Similarity
+		voting			VotingScheme
+		hierarchical	bool
+	}{
+		{SharedWords, UniformVote, false},
+		{Jaccard, DistanceVote, true},
+		{Cosine, SimilarityVote, false},
+	}
+	for _, test := range tests {
+		t.Run(test.metric.String()+" "+test.voting.String(),
+			func(t *testing.T) {
+			kc, err := KNNLearnData(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kc.SetMetric(test.metric)
+			kc.SetVoting(test.voting)
+			kc.SetHierarchical(test.hierarchical)
+			var buf, again bytes.Buffer
+			if _, err := kc.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := kc.WriteTo(&again); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+				t.Error("the index is not written identically twice")
+			}
+			loaded, err := ReadKNNClassifier(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Metric() != test.metric ||
+				loaded.Voting() != test.voting ||
+				loaded.Hierarchical() != test.hierarchical ||
+				loaded.Learned() != kc.Learned() ||
+				!reflect.DeepEqual(loaded.data, kc.data) {
+				t.Errorf("loaded %v %v %d, want %v %v %d", loaded.Metric(),
+					loaded.Voting(), loaded.Learned(), test.metric,
+					test.voting, kc.Learned())
+			}
+			for _, q := range queries {
+				for _, k := range []int{1, 5} {
+					got, err := loaded.KNNPredictLineage(q.Words, k)
+					if err != nil {
+						t.Fatal(err)
+					}
+					want, err := kc.KNNPredictLineage(q.Words, k)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !reflect.DeepEqual(got, want) ||
+						got.Lineage != q.Lineage {
+						t.Errorf("%s, k = %d: loaded index predicted %v, "+
+							"index %v, want %v", q.Id, k, got.Lineage,
+							want.Lineage, q.Lineage)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadKNNClassifierUntrained(t *testing.T) {
+	kc, err := NewKNNClassifier(DefaultKmerConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := kc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKNNClassifier(&buf); err != ErrUntrainedModel {
+		t.Errorf("got %v, want ErrUntrainedModel", err)
+	}
+}
+
+func TestKNNPredictInvalidK(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	kc, err := KNNLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kc.KNNPredictLineage(queries[0].Words, 0); err !=
+		ErrInvalidK {
+		t.Errorf("got %v, want ErrInvalidK", err)
+	}
+}
+
+func TestFindKMax(t *testing.T) {
+	a, b, c := &Species{Id: "a"}, &Species{Id: "b"}, &Species{Id: "c"}
+	scores := map[*Species]float64{a: 2, b: 3, c: 2}
+	tests := []struct {
+		name	string
+		s		map[*Species]float64
+		k		int
+		want	[]*Species
+	}{
+		{"k of 1", scores, 1, []*Species{b}},
+		{"tie to the smaller ID", scores, 2, []*Species{b, a}},
+		{"all", scores, 3, []*Species{b, a, c}},
+		{"fewer than k", scores, 5, []*Species{b, a, c}},
+		{"none", map[*Species]float64{}, 3, []*Species{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := FindKMax(test.s, test.k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+	if _, err := FindKMax(scores, 0); err != ErrInvalidK {
+		t.Errorf("got %v, want ErrInvalidK", err)
+	}
+}
+
+// Species of the same ID and score are ranked by learning position, as by
+// nearer(), whatever their order in the input.
+func TestFindKMaxDuplicateIds(t *testing.T) {
+	a, twin, late := &Species{Id: "a"}, &Species{Id: "a"}, &Species{Id: "a"}
+	b := &Species{Id: "b"}
+	orders := [][]Item{
+		{{value: late, priority: 1, position: 3},
+			{value: twin, priority: 1, position: 2},
+			{value: a, priority: 1, position: 0},
+			{value: b, priority: 1, position: 1}},
+		{{value: b, priority: 1, position: 1},
+			{value: a, priority: 1, position: 0},
+			{value: twin, priority: 1, position: 2},
+			{value: late, priority: 1, position: 3}},
+	}
+	for i, items := range orders {
+		for k, want := range [][]*Species{{a}, {a, twin}, {a, twin, late},
+			{a, twin, late, b}} {
+			// The species are compared as pointers, their fields being
+			// the same.
+			got := findKMax(append([]Item(nil), items...), k+1)
+			same := len(got) == len(want)
+			for j := 0; same && j < len(got); j++ {
+				same = got[j] == want[j]
+			}
+			if !same {
+				t.Errorf("order %d, k = %d: got %p, want %p", i, k+1, got,
+					want)
+			}
+		}
+	}
+}
+
+// A sequence sharing no word with the index is predicted as NoHit, and one
+// sharing words with fewer than k species is predicted from those.
+func TestKNNPredictFewHits(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	species := []Species{
+		newTestSpecies("a", "GenusA", randomSequence(r, 300),
+			DefaultKmerConfig),
+	}
+	kc, err := KNNLearnData(NewRawData(&species, DefaultKmerConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := kc.KNNPredictLineage(species[0].Words, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Class != "GenusA" || len(p.Neighbours) != 1 ||
+		p.Confidence != 0.2 {
+		t.Errorf("got %s from %d neighbours with confidence %g, want "+
+			"GenusA from 1 with 0.2", p.Class, len(p.Neighbours),
+			p.Confidence)
+	}
+	p, err = kc.KNNPredictLineage([]Word{}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Class != NoHit || len(p.Neighbours) != 0 || len(p.Votes) != 0 {
+		t.Errorf("got %+v, want NoHit", p)
+	}
+}
+
+// Two neighbours of a genus of one family are outvoted by the three 
+// neighbours of three genera of another family: the genus with the most 
+// votes must still be predicted, unless the lineage is voted rank by rank.
+func TestKNNVoteByClass(t *testing.T) {
+	candidates, hits := neighbours([]Class{"A", "A", "B", "Cg", "D"},
+		[]float64{0.5, 0.5, 0.5, 0.5, 0.5})
+	for _, spe := range candidates {
+		family := "Y"
+		if spe.Class == "A" {
+			family = "X"
+		}
+		spe.Lineage = ParseLineage("Bacteria;Firmicutes;Bacilli;" +
+			"Bacillales;" + family + ";" + string(spe.Class) + ";" +
+			string(spe.Class) + " sp.")
+	}
+	tests := []struct {
+		hierarchical		bool
+		class, family		Class
+		confidence			float64
+		familyConfidence	float64
+	}{
+		{false, "A", "X", 0.4, 0.4},
+		{true, "B", "Y", 0.2, 0.6},
+	}
+	for _, test := range tests {
+		kc := &KNNClassifier{hierarchical: test.hierarchical}
+		p := kc.vote(candidates, hits, 5)
+		if p.Class != test.class || p.Lineage[RankGenus] != test.class ||
+			p.Lineage[RankFamily] != test.family ||
+			p.Confidence != test.confidence ||
+			p.RankConfidence[RankFamily] != test.familyConfidence {
+			t.Errorf("hierarchical %v: predicted %s (%g) of family %s "+
+				"(%g), want %s (%g) of %s (%g)", test.hierarchical, p.Class,
+				p.Confidence, p.Lineage[RankFamily],
+				p.RankConfidence[RankFamily], test.class, test.confidence,
+				test.family, test.familyConfidence)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package classifier
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// A Word is a k-mer packed with 2 bits per base (A=0, C=1, G=2, T=3), the
+// first base in the highest bits, so k-mers of up to 32 bases fit.
+type Word uint64
+
+const (
+	MaxK		= 32
+	DefaultK	= 8
+	// Counts of words are kept in dense arrays up to this k (4^10 words).
+	maxDenseK	= 10
+)
+
+// AmbiguityPolicy tells what to do with k-mers containing IUPAC ambiguity
+// codes such as N, R or Y.
+type AmbiguityPolicy int
+
+const (
+	// Skip k-mers containing an ambiguous base.
+	SkipAmbiguous AmbiguityPolicy = iota
+	// Replace a k-mer containing ambiguous bases by every k-mer it may
+	// stand for, unless there are more than MaxExpansions of them.
+	ExpandAmbiguous
+)
+
+var ambiguityNames = []string{"skip", "expand"}
+
+func (a AmbiguityPolicy) String() string {
+	return nameOf(ambiguityNames, int(a))
+}
+
+func (a *AmbiguityPolicy) Set(name string) error {
+	i, err := parseName(ambiguityNames, name, "ambiguity policy")
+	if err == nil {
+		*a = AmbiguityPolicy(i)
+	}
+	return err
+}
+
+// KmerConfig holds the settings used to turn a sequence into words. It is
+// stored with every classifier, so that a query is processed exactly like
+// the training data.
+type KmerConfig struct {
+	K 				int
+	Ambiguity		AmbiguityPolicy
+	MaxExpansions	int
+	// Use canonical k-mers (the smaller of a k-mer and its reverse 
+	// complement), so that both strands of a sequence give the same words.
+	Canonical		bool
+}
+
+var DefaultKmerConfig = KmerConfig{K: DefaultK, Ambiguity: SkipAmbiguous, 
+	MaxExpansions: 16}
+
+// Check() returns an error wrapping ErrInvalidConfig if the settings are 
+// out of range.
+func (c KmerConfig) Check() error {
+	if c.K < 1 || c.K > MaxK {
+		return fmt.Errorf("%w: k-mer length %d is not between 1 and %d", 
+			ErrInvalidConfig, c.K, MaxK)
+	}
+	if c.Ambiguity == ExpandAmbiguous && c.MaxExpansions < 1 {
+		return fmt.Errorf("%w: at least one expansion of ambiguous k-mers "+
+			"must be allowed", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// NumWords() returns the number of possible words, 4^k. It is only used for
+// dense k, as 4^32 does not fit.
+func (c KmerConfig) NumWords() uint64 {
+	return 1 << uint(2*c.K)
+}
+
+// isDense() tells if words of this length are counted in dense arrays.
+func (c KmerConfig) isDense() bool {
+	return c.K <= maxDenseK
+}
+
+// The bases an IUPAC code may stand for, one bit per base (A=1, C=2, G=4, 
+// T=8). Lowercase letters mean the same as uppercase ones, and U is read as
+// T. Anything else is not a base.
+var baseMask = [256]uint8{}
+
+// The 2-bit code of the unambiguous bases, or -1.
+var baseCode = [256]int8{}
+
+func init() {
+	codes := map[byte]uint8{
+		'A': 1, 'C': 2, 'G': 4, 'T': 8, 'U': 8,
+		'R': 1 | 4, 'Y': 2 | 8, 'S': 2 | 4, 'W': 1 | 8, 'K': 4 | 8, 
+		'M': 1 | 2, 'B': 2 | 4 | 8, 'D': 1 | 4 | 8, 'H': 1 | 2 | 8, 
+		'V': 1 | 2 | 4, 'N': 1 | 2 | 4 | 8,
+	}
+	for i := range baseCode {
+		baseCode[i] = -1
+	}
+	for base, mask := range codes {
+		lower := base + 'a' - 'A'
+		baseMask[base], baseMask[lower] = mask, mask
+		if bits.OnesCount8(mask) == 1 {
+			code := int8(bits.TrailingZeros8(mask))
+			baseCode[base], baseCode[lower] = code, code
+		}
+	}
+}
+
+// This function generates the distinct k-mers of a sequence, sorted. The
+// words are encoded with a rolling 2-bit encoder. Case is ignored, U is read
+// as T and alignment gaps ("-" and ".") are removed. K-mers containing 
+// ambiguous bases are skipped or expanded, as the settings say, and k-mers 
+// containing anything else are skipped. With canonical k-mers, every word is
+// replaced by the smaller of itself and its reverse complement.
+func GenerateWords(sequence string, cfg KmerConfig) []Word {
+	return uniqueWords(extractWords(sequence, cfg))
+}
+
+// Features are the distinct words of a sequence, sorted, together with the
+// number of times each of them occurs. Counts may be nil, in which case 
+// every word counts once.
+type Features struct {
+	Words		[]Word
+	Counts		[]int32
+}
+
+// GenerateFeatures() generates the words of a sequence like GenerateWords(),
+// and counts them.
+func GenerateFeatures(sequence string, cfg KmerConfig) Features {
+	return countWords(extractWords(sequence, cfg))
+}
+
+// countWords() sorts words in place and counts them.
+func countWords(words []Word) Features {
+	sort.Slice(words, func(i, j int) bool { return words[i] < words[j] })
+	f := Features{Words: words[:0], Counts: make([]int32, 0, len(words))}
+	for _, word := range words {
+		if n := len(f.Words); n > 0 && word == f.Words[n-1] {
+			f.Counts[n-1]++
+			continue
+		}
+		f.Words = append(f.Words, word)
+		f.Counts = append(f.Counts, 1)
+	}
+	return f
+}
+
+// Count() returns the count of the i-th word.
+func (f Features) Count(i int) int32 {
+	if f.Counts == nil {
+		return 1
+	}
+	return f.Counts[i]
+}
+
+// extractWords() returns every k-mer of a sequence, in order, as described
+// for GenerateWords().
+func extractWords(sequence string, cfg KmerConfig) []Word {
+	k := cfg.K
+	mask := Word(1)<<uint(2*k) - 1
+	if k == MaxK {
+		mask = ^Word(0)
+	}
+	words := make([]Word, 0, len(sequence))
+	var word Word
+	var window [MaxK]uint8
+	length := 0
+	lastAmbiguous := -1
+	for i := 0; i < len(sequence); i++ {
+		if sequence[i] == '-' || sequence[i] == '.' {
+			continue
+		}
+		bases := baseMask[sequence[i]]
+		if bases == 0 {
+			length = 0
+			lastAmbiguous = -1
+			continue
+		}
+		code := baseCode[sequence[i]]
+		if code < 0 {
+			lastAmbiguous = length
+			code = 0
+		}
+		window[length%k] = bases
+		word = (word<<2 | Word(code)) & mask
+		length++
+		if length < k {
+			continue
+		}
+		if lastAmbiguous < 0 || lastAmbiguous <= length-1-k {
+			words = append(words, word)
+		} else if cfg.Ambiguity == ExpandAmbiguous {
+			words = expandWord(words, window[:k], length%k, 
+				cfg.MaxExpansions)
+		}
+	}
+	if cfg.Canonical {
+		for i, word := range words {
+			words[i] = canonicalWord(word, k)
+		}
+	}
+	return words
+}
+
+// reverseComplement() returns the reverse complement of a word of length k.
+// The complement of a base is 3 minus its code.
+func reverseComplement(word Word, k int) Word {
+	var rc Word
+	for i := 0; i < k; i++ {
+		rc = rc<<2 | (3 - word&3)
+		word >>= 2
+	}
+	return rc
+}
+
+func canonicalWord(word Word, k int) Word {
+	if rc := reverseComplement(word, k); rc < word {
+		return rc
+	}
+	return word
+}
+
+var complementBase = [256]byte{}
+
+func init() {
+	pairs := []string{"AT", "UA", "CG", "RY", "KM", "BV", "DH", "SS", "WW", 
+		"NN"}
+	for i := range complementBase {
+		complementBase[i] = byte(i)
+	}
+	for _, pair := range pairs {
+		a, b := pair[0], pair[1]
+		complementBase[a] = b
+		complementBase[a+'a'-'A'] = b + 'a' - 'A'
+		if a != 'U' {
+			complementBase[b] = a
+			complementBase[b+'a'-'A'] = a + 'a' - 'A'
+		}
+	}
+}
+
+// ReverseComplement() returns the reverse complement of a sequence. IUPAC 
+// ambiguity codes are complemented too, and case is kept.
+func ReverseComplement(sequence string) string {
+	n := len(sequence)
+	rc := make([]byte, n)
+	for i := 0; i < n; i++ {
+		rc[n-1-i] = complementBase[sequence[i]]
+	}
+	return string(rc)
+}
+
+// expandWord() appends every k-mer an ambiguous k-mer may stand for, unless
+// there are more than maxExpansions of them. The k-mer is held in a ring 
+// buffer whose first base is at position start.
+func expandWord(words []Word, window []uint8, start, 
+	maxExpansions int) []Word {
+	k := len(window)
+	numOfExpansions := 1
+	for _, bases := range window {
+		numOfExpansions *= bits.OnesCount8(bases)
+		if numOfExpansions > maxExpansions {
+			return words
+		}
+	}
+	first := len(words)
+	words = append(words, 0)
+	for i := 0; i < k; i++ {
+		bases := window[(start+i)%k]
+		last := len(words)
+		for j := first; j < last; j++ {
+			prefix := words[j] << 2
+			isFirst := true
+			for code := 0; code < 4; code++ {
+				if bases&(1<<uint(code)) == 0 {
+					continue
+				}
+				if isFirst {
+					words[j] = prefix | Word(code)
+					isFirst = false
+				} else {
+					words = append(words, prefix|Word(code))
+				}
+			}
+		}
+	}
+	return words
+}
+
+// uniqueWords() sorts words and removes duplicates in place.
+func uniqueWords(words []Word) []Word {
+	sort.Slice(words, func(i, j int) bool { return words[i] < words[j] })
+	n := 0
+	for i, word := range words {
+		if i == 0 || word != words[n-1] {
+			words[n] = word
+			n++
+		}
+	}
+	return words[:n]
+}
+
+// Decode() decodes a word of length k back into bases.
+func (w Word) Decode(k int) string {
+	bases := make([]byte, k)
+	for i := k - 1; i >= 0; i-- {
+		bases[i] = "ACGT"[w&3]
+		w >>= 2
+	}
+	return string(bases)
+}
+
+// WordCounts counts words. For small k the counts are kept in a dense array
+// indexed by the word itself, otherwise in a map.
+type WordCounts struct {
+	Dense		[]int32
+	Sparse		map[Word]int32
+}
+
+func NewWordCounts(cfg KmerConfig) *WordCounts {
+	if cfg.isDense() {
+		return &WordCounts{Dense: make([]int32, cfg.NumWords())}
+	}
+	return &WordCounts{Sparse: make(map[Word]int32)}
+}
+
+func (wc *WordCounts) Get(word Word) int {
+	if wc.Dense != nil {
+		return int(wc.Dense[word])
+	}
+	return int(wc.Sparse[word])
+}
+
+func (wc *WordCounts) Add(word Word, n int) {
+	if wc.Dense != nil {
+		wc.Dense[word] += int32(n)
+	} else {
+		wc.Sparse[word] += int32(n)
+	}
+}
+
+// Merge() adds the counts of other, which must be of the same k, to wc.
+func (wc *WordCounts) Merge(other *WordCounts) {
+	for word, n := range other.Dense {
+		if n != 0 {
+			wc.Add(Word(word), int(n))
+		}
+	}
+	for word, n := range other.Sparse {
+		wc.Add(word, int(n))
+	}
+}
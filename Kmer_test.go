@@ -0,0 +1,149 @@
+package classifier
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// decodeWords() decodes words of length k, for readable comparisons.
+func decodeWords(words []Word, k int) []string {
+	kmers := make([]string, len(words))
+	for i, word := range words {
+		kmers[i] = word.Decode(k)
+	}
+	return kmers
+}
+
+func TestGenerateWords(t *testing.T) {
+	expand := KmerConfig{K: 3, Ambiguity: ExpandAmbiguous, MaxExpansions: 16}
+	tests := []struct {
+		name		string
+		sequence	string
+		cfg			KmerConfig
+		want		[]string
+	}{
+		{"plain", "ACGTA", KmerConfig{K: 3}, []string{"ACG", "CGT", "GTA"}},
+		{"lowercase and uracil", "acgua", KmerConfig{K: 3},
+			[]string{"ACG", "CGT", "GTA"}},
+		{"alignment gaps", "AC-G.TA", KmerConfig{K: 3},
+			[]string{"ACG", "CGT", "GTA"}},
+		{"repeated k-mers", "AAAAA", KmerConfig{K: 3}, []string{"AAA"}},
+		{"ambiguous base skipped", "ACNGTA", KmerConfig{K: 3},
+			[]string{"GTA"}},
+		{"ambiguous base expanded", "ACR", expand, []string{"ACA", "ACG"}},
+		{"two ambiguous bases expanded", "YAR", expand,
+			[]string{"CAA", "CAG", "TAA", "TAG"}},
+		{"too many expansions", "NNN", expand, []string{}},
+		{"non-base breaks the sequence", "ACGXACGT", KmerConfig{K: 3},
+			[]string{"ACG", "CGT"}},
+		{"shorter than k", "AC", KmerConfig{K: 3}, []string{}},
+		{"canonical", "TTTGCA", KmerConfig{K: 3, Canonical: true},
+			[]string{"AAA", "CAA", "GCA"}},
+		{"k of 1", "GATTACA", KmerConfig{K: 1}, []string{"A", "C", "G", "T"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := decodeWords(GenerateWords(test.sequence, test.cfg),
+				test.cfg.K)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GenerateWords(%q) = %v, want %v", test.sequence,
+					got, test.want)
+			}
+		})
+	}
+}
+
+// The rolling encoder must give the same words as encoding every substring
+// of length k on its own, up to the longest k.
+func TestGenerateWordsMatchesSubstrings(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sequence := randomSequence(r, 200)
+	for _, k := range []int{1, 2, 8, 16, 31, MaxK} {
+		want := make(map[string]bool)
+		for i := 0; i+k <= len(sequence); i++ {
+			want[sequence[i:i+k]] = true
+		}
+		wantKmers := make([]string, 0, len(want))
+		for kmer := range want {
+			wantKmers = append(wantKmers, kmer)
+		}
+		sort.Strings(wantKmers)
+		got := decodeWords(GenerateWords(sequence, KmerConfig{K: k}), k)
+		if !reflect.DeepEqual(got, wantKmers) {
+			t.Errorf("k = %d: got %d words, want %d", k, len(got),
+				len(wantKmers))
+		}
+	}
+}
+
+// Dense and sparse counts must count alike.
+func TestWordCounts(t *testing.T) {
+	for _, k := range []int{4, maxDenseK, maxDenseK + 1, MaxK} {
+		wc := NewWordCounts(KmerConfig{K: k})
+		if (wc.Dense != nil) != (k <= maxDenseK) {
+			t.Errorf("k = %d: dense is %v", k, wc.Dense != nil)
+		}
+		words := GenerateWords("ACGTTGCAACGT", KmerConfig{K: 4})
+		for i, word := range words {
+			wc.Add(word, i+1)
+			wc.Add(word, 1)
+		}
+		for i, word := range words {
+			if got := wc.Get(word); got != i+2 {
+				t.Errorf("k = %d: count of %s = %d, want %d", k,
+					word.Decode(4), got, i+2)
+			}
+		}
+	}
+}
+
+// Canonical k-mers make a sequence and its reverse complement give the
+// same words.
+func TestCanonicalWordsOfBothStrands(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, k := range []int{3, 8, MaxK} {
+		cfg := KmerConfig{K: k, Canonical: true}
+		sequence := randomSequence(r, 300)
+		forward := GenerateWords(sequence, cfg)
+		reverse := GenerateWords(ReverseComplement(sequence), cfg)
+		if !reflect.DeepEqual(forward, reverse) {
+			t.Errorf("k = %d: the strands give different words", k)
+		}
+	}
+}
+
+func TestReverseComplement(t *testing.T) {
+	tests := []struct {
+		sequence	string
+		want		string
+	}{
+		{"ACGT", "ACGT"},
+		{"AACG", "CGTT"},
+		{"acgu", "acgt"},
+		{"ARYN", "NRYT"},
+	}
+	for _, test := range tests {
+		if got := ReverseComplement(test.sequence); got != test.want {
+			t.Errorf("ReverseComplement(%q) = %q, want %q", test.sequence,
+				got, test.want)
+		}
+	}
+}
+
+func TestAmbiguityPolicyFlag(t *testing.T) {
+	for _, name := range ambiguityNames {
+		var a AmbiguityPolicy
+		if err := a.Set(name); err != nil || a.String() != name {
+			t.Errorf("Set(%q) gives %s, %v", name, a, err)
+		}
+	}
+	var a AmbiguityPolicy
+	if err := a.Set("guess"); err == nil {
+		t.Error("Set(\"guess\") did not fail")
+	}
+	if got := AmbiguityPolicy(len(ambiguityNames)).String(); got != "unknown" {
+		t.Errorf("String() of an unknown policy = %q", got)
+	}
+}
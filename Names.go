@@ -0,0 +1,27 @@
+package classifier
+
+import "fmt"
+
+// The options chosen by name, such as AmbiguityPolicy or BayesModel, are 
+// indices in a table of names. Their String() and Set() methods look the 
+// table up with nameOf() and parseName(), so that they can be used as 
+// flags.
+
+// nameOf() returns the name of option i, or "unknown" if it is out of range.
+func nameOf(names []string, i int) string {
+	if i >= 0 && i < len(names) {
+		return names[i]
+	}
+	return "unknown"
+}
+
+// parseName() returns the option called name. what tells the kind of the
+// option in the error.
+func parseName(names []string, name, what string) (int, error) {
+	for i, optionName := range names {
+		if name == optionName {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s %q", what, name)
+}
@@ -0,0 +1,128 @@
+package classifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// QueryNeighbours holds the nearest reference species found for one query
+// sequence, the nearest first.
+type QueryNeighbours struct {
+	Query			string			`json:"query"`
+	Neighbours		[]Neighbour		`json:"neighbours"`
+}
+
+// NeighboursFile() finds the k nearest reference species of every read of
+// a FASTA/FASTQ file, and writes them to outfile as soon as they are found,
+// in the order of the file: as tab-separated rows (see WriteNeighboursTSV())
+// or, if asJSON, as a JSON array (see WriteNeighboursJSON()). It returns the
+// number of reads searched, and the first error met; the neighbours of the
+// reads searched before it are written all the same.
+func NeighboursFile(file io.Reader, outfile io.Writer, kc *KNNClassifier,
+	k int, asJSON bool) (count int, err error) {
+	reader := NewSequenceReader(file)
+	writer := bufio.NewWriter(outfile)
+	defer func() {
+		if asJSON {
+			writeJSONEnd(writer, count)
+		}
+		if flushErr := writer.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	if !asJSON {
+		writeNeighboursHeader(writer)
+	}
+	for {
+		s, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		neighbours, err := kc.Nearest(GenerateFeatures(s.Sequence,
+			kc.Config()), k)
+		if err != nil {
+			return count, err
+		}
+		q := QueryNeighbours{Query: s.Id, Neighbours: neighbours}
+		if asJSON {
+			err = writeJSONElement(writer, q, count)
+		} else {
+			writeNeighboursRows(writer, q)
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// WriteNeighboursTSV() writes the neighbours of every query as
+// tab-separated rows (query, rank, reference ID, shared words, similarity,
+// distance, taxonomy) after a header line. Ranks start at 1 for the nearest
+// neighbour. A query without neighbours gets a single row of rank 0 whose 
+// ID is NoHit.
+func WriteNeighboursTSV(w io.Writer, results []QueryNeighbours) error {
+	writer := bufio.NewWriter(w)
+	writeNeighboursHeader(writer)
+	for _, q := range results {
+		writeNeighboursRows(writer, q)
+	}
+	return writer.Flush()
+}
+
+// writeNeighboursHeader() writes the header line of WriteNeighboursTSV().
+func writeNeighboursHeader(writer *bufio.Writer) {
+	fmt.Fprintln(writer, "query\trank\tid\tshared\tsimilarity\tdistance\t"+
+		"taxonomy")
+}
+
+// writeNeighboursRows() writes the rows of a query, see 
+// WriteNeighboursTSV().
+func writeNeighboursRows(writer *bufio.Writer, q QueryNeighbours) {
+	if len(q.Neighbours) == 0 {
+		fmt.Fprintf(writer, "%s\t0\t%s\t0\t0\t1\t\n", q.Query, NoHit)
+	}
+	for i, n := range q.Neighbours {
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%d\t%.4f\t%.4f\t%s\n", q.Query,
+			i+1, n.Id, n.Shared, n.Similarity, n.Distance, n.Taxonomy)
+	}
+}
+
+// WriteNeighboursJSON() writes the neighbours of every query as an indented
+// JSON array.
+func WriteNeighboursJSON(w io.Writer, results []QueryNeighbours) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// writeJSONElement() writes the i-th query of the array written by 
+// NeighboursFile(), indented as by WriteNeighboursJSON().
+func writeJSONElement(writer *bufio.Writer, q QueryNeighbours, i int) error {
+	data, err := json.MarshalIndent(q, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	if i == 0 {
+		writer.WriteString("[\n  ")
+	} else {
+		writer.WriteString(",\n  ")
+	}
+	writer.Write(data)
+	return nil
+}
+
+// writeJSONEnd() closes the array of n queries written by NeighboursFile().
+func writeJSONEnd(writer *bufio.Writer, n int) {
+	if n == 0 {
+		writer.WriteString("[]\n")
+	} else {
+		writer.WriteString("\n]\n")
+	}
+}
@@ -0,0 +1,122 @@
+package classifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestNearest(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	kc, err := KNNLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, spe := range d.species[:5] {
+		neighbours, err := kc.Nearest(spe.Features(), 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(neighbours) != 5 {
+			t.Fatalf("%s: got %d neighbours, want 5", spe.Id,
+				len(neighbours))
+		}
+		if neighbours[0].Id != spe.Id ||
+			neighbours[0].Shared != len(spe.Words) {
+			t.Errorf("%s: nearest is %s sharing %d words, want itself",
+				spe.Id, neighbours[0].Id, neighbours[0].Shared)
+		}
+		for i := 1; i < len(neighbours); i++ {
+			a, b := neighbours[i-1], neighbours[i]
+			if a.Similarity < b.Similarity ||
+				(a.Similarity == b.Similarity && a.Id >= b.Id) {
+				t.Errorf("%s: %s ranks before %s", spe.Id, a.Id, b.Id)
+			}
+		}
+	}
+	if _, err := kc.Nearest(d.species[0].Features(), 0); err !=
+		ErrInvalidK {
+		t.Errorf("got %v, want ErrInvalidK", err)
+	}
+}
+
+// NeighboursFile() must write what WriteNeighboursTSV() and 
+// WriteNeighboursJSON() write for the same queries, up to a malformed 
+// record.
+func TestNeighboursFile(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sequences := []string{randomSequence(r, 300), randomSequence(r, 300)}
+	species := []Species{
+		newTestSpecies("a", "GenusA", sequences[0], DefaultKmerConfig),
+		newTestSpecies("b", "GenusB", sequences[1], DefaultKmerConfig),
+	}
+	kc, err := KNNLearnData(NewRawData(&species, DefaultKmerConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make([]QueryNeighbours, 0)
+	for i, read := range []string{"read1", "read2"} {
+		n := len(species[i].Words)
+		results = append(results, QueryNeighbours{read, []Neighbour{{
+			species[i].Id, species[i].Taxonomy, species[i].Class, n,
+			float64(n), 0}}})
+	}
+	var tsv, jsonOut bytes.Buffer
+	if err := WriteNeighboursTSV(&tsv, results); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteNeighboursJSON(&jsonOut, results); err != nil {
+		t.Fatal(err)
+	}
+	var emptyJSON bytes.Buffer
+	if err := WriteNeighboursJSON(&emptyJSON, 
+		[]QueryNeighbours{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "query\trank\tid\tshared\tsimilarity\tdistance\ttaxonomy\n"
+	for i, read := range []string{"read1", "read2"} {
+		n := len(species[i].Words)
+		want += fmt.Sprintf("%s\t1\t%s\t%d\t%d.0000\t0.0000\t%s\n", read,
+			species[i].Id, n, n, species[i].Taxonomy)
+	}
+	if tsv.String() != want {
+		t.Errorf("WriteNeighboursTSV() wrote\n%s\nwant\n%s", tsv.String(),
+			want)
+	}
+	file := ">read1\n" + sequences[0] + "\n>read2\n" + sequences[1] + "\n"
+	// The quality line of the second read is too short.
+	malformed := "@read1\n" + sequences[0] + "\n+\n" +
+		strings.Repeat("I", 300) + "\n@read2\n" + sequences[1] + "\n+\nI\n"
+	firstRows := strings.SplitAfterN(tsv.String(), "\n", 3)
+	tests := []struct {
+		name	string
+		file	string
+		asJSON	bool
+		n		int
+		want	string
+		wantErr	error
+	}{
+		{"tsv", file, false, 2, tsv.String(), nil},
+		{"json", file, true, 2, jsonOut.String(), nil},
+		{"empty json", "", true, 0, emptyJSON.String(), nil},
+		{"malformed", malformed, false, 1, firstRows[0] + firstRows[1],
+			ErrMalformedRecord},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			n, err := NeighboursFile(strings.NewReader(test.file), &out, kc,
+				1, test.asJSON)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+			if n != test.n || out.String() != test.want {
+				t.Errorf("searched %d reads into\n%s\nwant %d into\n%s", n,
+					out.String(), test.n, test.want)
+			}
+		})
+	}
+}
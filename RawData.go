@@ -0,0 +1,162 @@
+package classifier
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+type RawData struct {
+	classes 	[]Class
+	species		[]*Species
+	classMap	map[Class]int
+	config		KmerConfig
+}
+
+// LoadRawData() reads a data set file, and generates the words of every
+// species with the given k-mer settings.
+func LoadRawData(dataSetName string, cfg KmerConfig) (*RawData, error) {
+	file, err := os.Open(dataSetName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadRawData(file, cfg)
+}
+
+// LoadRawDataFormat() is LoadRawData() for a data set whose identity lines
+// have the given format.
+func LoadRawDataFormat(dataSetName string, cfg KmerConfig, 
+	format HeaderFormat) (*RawData, error) {
+	file, err := os.Open(dataSetName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadRawDataFormat(file, cfg, format)
+}
+
+// ReadRawData() reads a data set, in which every species takes two lines:
+// its identity line, of any format AutoFormat() detects, and its sequence.
+// A malformed identity line is reported as a *HeaderError.
+func ReadRawData(file io.Reader, cfg KmerConfig) (*RawData, error) {
+	return ReadRawDataFormat(file, cfg, AutoFormat)
+}
+
+// ReadRawDataFormat() is ReadRawData() for a data set whose identity lines
+// have the given format (AutoFormat() if nil).
+func ReadRawDataFormat(file io.Reader, cfg KmerConfig, 
+	format HeaderFormat) (*RawData, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	species := make([]Species, 0)
+	isFirst := true
+	s := Species{Words: make([]Word, 0)}
+	line := 0
+	for scanner.Scan() {
+		line++
+		temp_string := scanner.Text()
+		if strings.HasPrefix(temp_string, ">") {
+			if isFirst {
+				isFirst = false
+			} else {
+				species = append(species, s)
+				s = Species{Words: make([]Word, 0)}
+			}
+			if err := s.parseHeader(temp_string, format); err != nil {
+				err.(*HeaderError).Line = line
+				return nil, err
+			}
+		} else {
+			s.Sequence = temp_string
+		}
+	}
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+	if !isFirst {
+		species = append(species, s)
+	}
+	for i:= 0; i < len(species); i++ {
+		f := GenerateFeatures(species[i].Sequence, cfg)
+		species[i].Words, species[i].Counts = f.Words, f.Counts
+	}
+	return NewRawData(&species, cfg), nil
+}
+
+func NewRawData(species *[]Species, cfg KmerConfig) *RawData {
+	d := &RawData{
+		make([]Class, 0),
+		make([]*Species, 0),
+		make(map[Class]int),
+		cfg,
+	}
+	for i := 0; i < len(*species); i++ {
+		d.species = append(d.species, &(*species)[i])
+		d.classMap[(*species)[i].Class]++
+	}
+
+	for class, _ := range d.classMap {
+		d.classes = append(d.classes, class)
+	}
+	return d
+}
+
+// Species() returns the species of the data set.
+func (d *RawData) Species() []*Species {
+	return d.species
+}
+
+// Classes() returns the distinct classes of the data set.
+func (d *RawData) Classes() []Class {
+	return d.classes
+}
+
+// ClassCount() returns the number of species of a class.
+func (d *RawData) ClassCount(class Class) int {
+	return d.classMap[class]
+}
+
+// Config() returns the k-mer settings the words were generated with.
+func (d *RawData) Config() KmerConfig {
+	return d.config
+}
+
+// subset() returns a data set holding the species of d with the given ids,
+// sharing the species and their words.
+func (d *RawData) subset(ids []int) *RawData {
+	sub := &RawData{
+		make([]Class, 0),
+		make([]*Species, 0, len(ids)),
+		make(map[Class]int),
+		d.config,
+	}
+	for _, id := range ids {
+		spe := d.species[id]
+		if sub.classMap[spe.Class] == 0 {
+			sub.classes = append(sub.classes, spe.Class)
+		}
+		sub.species = append(sub.species, spe)
+		sub.classMap[spe.Class]++
+	}
+	return sub
+}
+
+// without() returns a data set holding the species of d except the ones 
+// with the given ids.
+func (d *RawData) without(ids []int) *RawData {
+	isExcluded := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		isExcluded[id] = true
+	}
+	kept := make([]int, 0, len(d.species)-len(ids))
+	for id := range d.species {
+		if !isExcluded[id] {
+			kept = append(kept, id)
+		}
+	}
+	return d.subset(kept)
+}
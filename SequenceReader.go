@@ -0,0 +1,88 @@
+package classifier
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A SequenceReader streams the records of a FASTA or FASTQ file one at a
+// time, so that a whole sequencing run never has to be held in memory.
+type SequenceReader struct {
+	scanner 	*bufio.Scanner
+	header		string
+}
+
+func NewSequenceReader(file io.Reader) *SequenceReader {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &SequenceReader{scanner: scanner}
+}
+
+// Read() returns the next record of the file, or io.EOF when the file is
+// finished. Multi-line FASTA sequences are joined, and FASTQ quality lines
+// are skipped. A FASTQ record whose quality does not match its sequence is
+// reported as ErrMalformedRecord.
+func (r *SequenceReader) Read() (*Species, error) {
+	for r.header == "" {
+		if !r.scanner.Scan() {
+			if r.scanner.Err() != nil {
+				return nil, r.scanner.Err()
+			}
+			return nil, io.EOF
+		}
+		r.header = strings.TrimSpace(r.scanner.Text())
+	}
+	s := &Species{Words: make([]Word, 0)}
+	s.ReadIdHelper(r.header)
+	isFASTQ := strings.HasPrefix(r.header, "@")
+	r.header = ""
+	if isFASTQ {
+		return s, r.readFASTQ(s)
+	}
+	return s, r.readFASTA(s)
+}
+
+// This is a subroutine of Read(). It collects the sequence lines up to the
+// next identity line.
+func (r *SequenceReader) readFASTA(s *Species) error {
+	lines := make([]string, 0)
+	for r.scanner.Scan() {
+		temp_string := strings.TrimSpace(r.scanner.Text())
+		if strings.HasPrefix(temp_string, ">") {
+			r.header = temp_string
+			break
+		}
+		lines = append(lines, temp_string)
+	}
+	s.Sequence = strings.Join(lines, "")
+	return r.scanner.Err()
+}
+
+// This is a subroutine of Read(). It collects the sequence lines up to the
+// "+" separator, and then skips as many quality characters as there are
+// bases.
+func (r *SequenceReader) readFASTQ(s *Species) error {
+	lines := make([]string, 0)
+	for r.scanner.Scan() {
+		temp_string := strings.TrimSpace(r.scanner.Text())
+		if strings.HasPrefix(temp_string, "+") {
+			break
+		}
+		lines = append(lines, temp_string)
+	}
+	s.Sequence = strings.Join(lines, "")
+	quality := 0
+	for quality < len(s.Sequence) && r.scanner.Scan() {
+		quality += len(strings.TrimSpace(r.scanner.Text()))
+	}
+	if r.scanner.Err() != nil {
+		return r.scanner.Err()
+	}
+	if quality != len(s.Sequence) {
+		return fmt.Errorf("%w: the quality line of read %s does not match "+
+			"its sequence", ErrMalformedRecord, s.Id)
+	}
+	return nil
+}
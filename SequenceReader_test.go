@@ -0,0 +1,78 @@
+package classifier
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSequenceReader(t *testing.T) {
+	type record struct {
+		id			string
+		class		Class
+		sequence	string
+	}
+	tests := []struct {
+		name	string
+		file	string
+		want	[]record
+	}{
+		{"SILVA FASTA", ">AB1 Bacteria;Firmicutes;Bacilli;Bacillales;" +
+			"Bacillaceae;Bacillus;Bacillus subtilis\nACGT\nTTGA\n" +
+			">AB2 Bacteria;Firmicutes;Listeria;Listeria sp.\nGGCC\n",
+			[]record{{"AB1", "Bacillus", "ACGTTTGA"},
+			{"AB2", "Listeria", "GGCC"}}},
+		{"read FASTA", "\n>read1 length=8 sample=3\nACGT\nTTGA\n>read2\n" +
+			"GG\n", []record{{"read1", "", "ACGTTTGA"}, {"read2", "", "GG"}}},
+		{"FASTQ", "@read1 1:N:0\nACGT\n+\n@III\n@read2\nACG\nTT\n+read2\n" +
+			"II\nIII\n", []record{{"read1", "", "ACGT"},
+			{"read2", "", "ACGTT"}}},
+		{"empty", "", []record{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := NewSequenceReader(strings.NewReader(test.file))
+			got := make([]record, 0)
+			for {
+				s, err := reader.Read()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, record{s.Id, s.Class, s.Sequence})
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("got %d records, want %d", len(got),
+					len(test.want))
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("record %d = %+v, want %+v", i, got[i],
+						test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSequenceReaderMalformed(t *testing.T) {
+	tests := []struct {
+		name	string
+		file	string
+	}{
+		{"short quality", "@read1\nACGT\n+\nIII\n"},
+		{"long quality", "@read1\nACGT\n+\nIIIII\n@read2\nAC\n+\nII\n"},
+		{"no quality", "@read1\nACGT\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := NewSequenceReader(strings.NewReader(test.file))
+			if _, err := reader.Read(); !errors.Is(err,
+				ErrMalformedRecord) {
+				t.Errorf("got %v, want ErrMalformedRecord", err)
+			}
+		})
+	}
+}
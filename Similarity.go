@@ -0,0 +1,96 @@
+package classifier
+
+import (
+	"math"
+)
+
+// Similarity tells how the kNN classifier measures how near a reference
+// species is to a query. It is stored with the classifier.
+type Similarity int
+
+const (
+	// The number of distinct words shared with the query.
+	SharedWords Similarity = iota
+	// The shared words divided by the words of either sequence.
+	Jaccard
+	// The fraction of the words of the query found in the reference, so
+	// that long references are not favoured.
+	Containment
+	// The cosine of the angle between the word count vectors.
+	Cosine
+	// The average nucleotide identity estimated from the Jaccard index, as
+	// in Mash (Ondov et al., 2016).
+	ANI
+)
+
+var similarityNames = []string{"shared", "jaccard", "containment", "cosine",
+	"ani"}
+
+func (m Similarity) String() string {
+	return nameOf(similarityNames, int(m))
+}
+
+func (m *Similarity) Set(name string) error {
+	i, err := parseName(similarityNames, name, "similarity")
+	if err == nil {
+		*m = Similarity(i)
+	}
+	return err
+}
+
+// A match holds what is known of a query and a reference sharing words:
+// the number of shared words, the dot product of their word counts, their
+// numbers of distinct words and the norms of their word counts.
+type match struct {
+	shared			int
+	dot				float64
+	querySize		int
+	refSize			int
+	queryNorm		float64
+	refNorm			float64
+}
+
+// score() returns the similarity of a match, for words of length k.
+func (m Similarity) score(mt match, k int) float64 {
+	switch m {
+	case Jaccard:
+		return jaccard(mt)
+	case Containment:
+		if mt.querySize == 0 {
+			return 0
+		}
+		return float64(mt.shared) / float64(mt.querySize)
+	case Cosine:
+		if mt.queryNorm == 0 || mt.refNorm == 0 {
+			return 0
+		}
+		return mt.dot / (mt.queryNorm * mt.refNorm)
+	case ANI:
+		// The Mash distance is -ln(2J / (1 + J)) / k.
+		j := jaccard(mt)
+		if j == 0 {
+			return 0
+		}
+		ani := 1 + math.Log(2*j/(1+j))/float64(k)
+		return math.Max(ani, 0)
+	}
+	return float64(mt.shared)
+}
+
+// distance() returns the distance of a match of the given similarity, from
+// 0 for identical sequences. For shared words, whose similarity is not 
+// bounded, it is one minus the containment.
+func (m Similarity) distance(mt match, similarity float64) float64 {
+	if m == SharedWords {
+		similarity = Containment.score(mt, 0)
+	}
+	return math.Max(1-similarity, 0)
+}
+
+func jaccard(mt match) float64 {
+	union := mt.querySize + mt.refSize - mt.shared
+	if union == 0 {
+		return 0
+	}
+	return float64(mt.shared) / float64(union)
+}
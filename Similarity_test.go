@@ -0,0 +1,47 @@
+package classifier
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarityScore(t *testing.T) {
+	mt := match{shared: 2, dot: 6, querySize: 4, refSize: 3, queryNorm: 2,
+		refNorm: 5}
+	tests := []struct {
+		metric	Similarity
+		mt		match
+		want	float64
+	}{
+		{SharedWords, mt, 2},
+		{Jaccard, mt, 0.4},
+		{Containment, mt, 0.5},
+		{Cosine, mt, 0.6},
+		{ANI, mt, 1 + math.Log(0.8/1.4)/8},
+		{SharedWords, match{}, 0},
+		{Jaccard, match{}, 0},
+		{Containment, match{}, 0},
+		{Cosine, match{}, 0},
+		{ANI, match{}, 0},
+	}
+	for _, test := range tests {
+		if got := test.metric.score(test.mt, 8); math.Abs(got-test.want) >
+			1e-9 {
+			t.Errorf("%s of %+v = %g, want %g", test.metric, test.mt, got,
+				test.want)
+		}
+	}
+}
+
+func TestSimilarityFlag(t *testing.T) {
+	for _, name := range similarityNames {
+		var m Similarity
+		if err := m.Set(name); err != nil || m.String() != name {
+			t.Errorf("Set(%q) gives %s, %v", name, m, err)
+		}
+	}
+	var m Similarity
+	if err := m.Set("euclid"); err == nil {
+		t.Error("Set(\"euclid\") did not fail")
+	}
+}
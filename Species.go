@@ -0,0 +1,370 @@
+package classifier
+
+import(
+	"fmt"
+	"os"
+	"io"
+	"strings"
+	"bufio"
+)
+
+//var dataSetName string = "sortedData.txt"
+
+type Species struct{
+	Id			string
+	Taxonomy	string
+	Sequence	string
+	Name 		string
+	Class 		Class 
+	Lineage		Lineage
+	Words 		[]Word
+	// The number of times each of Words occurs in the sequence.
+	Counts		[]int32
+}
+
+// Features() returns the words of the species with their counts.
+func (s *Species) Features() Features {
+	return Features{s.Words, s.Counts}
+}
+
+type Class string
+
+// The taxonomic ranks of a lineage, from domain down to genus. The genus is
+// the Class of a species.
+type Rank int
+
+const (
+	RankDomain Rank = iota
+	RankPhylum
+	RankClass
+	RankOrder
+	RankFamily
+	RankGenus
+	NumRanks int = iota
+)
+
+var rankNames = [NumRanks]string{"domain", "phylum", "class", "order", 
+	"family", "genus"}
+
+func (r Rank) String() string {
+	return rankNames[r]
+}
+
+// A Lineage holds the taxon of a species at every rank. Ranks missing from
+// the taxonomy are left empty.
+type Lineage [NumRanks]Class
+
+// ParseLineage() parses a SILVA taxonomy "Domain;...;Genus;Species" into a 
+// Lineage. The first taxon is the domain and the one before the species name
+// is the genus. The taxa in between fill phylum, class and order from the 
+// top, except that the last of them is always the family.
+func ParseLineage(taxonomy string) Lineage {
+	parts := strings.Split(taxonomy, ";")
+	if len(parts) < 2 {
+		return Lineage{}
+	}
+	return lineageOfTaxa(parts[:len(parts)-1])
+}
+
+// lineageOfTaxa() places taxa from the domain down to the genus in a 
+// Lineage, as ParseLineage() does.
+func lineageOfTaxa(taxa []string) Lineage {
+	var lineage Lineage
+	if len(taxa) == 0 {
+		return lineage
+	}
+	lineage[RankDomain] = Class(taxa[0])
+	if len(taxa) == 1 {
+		return lineage
+	}
+	lineage[RankGenus] = Class(taxa[len(taxa)-1])
+	middle := taxa[1:len(taxa)-1]
+	for i := 0; i < len(middle) && i < int(RankFamily-RankPhylum); i++ {
+		lineage[RankPhylum+Rank(i)] = Class(middle[i])
+	}
+	if len(middle) > int(RankFamily-RankPhylum) {
+		lineage[RankFamily] = Class(middle[len(middle)-1])
+	}
+	return lineage
+}
+
+// String() joins the non-empty taxa of a lineage with ";".
+func (l Lineage) String() string {
+	taxa := make([]string, 0, NumRanks)
+	for _, taxon := range l {
+		if taxon != "" {
+			taxa = append(taxa, string(taxon))
+		}
+	}
+	return strings.Join(taxa, ";")
+}
+
+// Unclassified is reported instead of a class when a prediction is not 
+// confident enough.
+const Unclassified Class = "unclassified"
+
+// NoHit is predicted by the kNN classifier for a sequence sharing no word 
+// with any reference species.
+const NoHit Class = "no_hit"
+
+// The strand of a sequence a prediction was made from.
+type Strand int
+
+const (
+	ForwardStrand Strand = iota
+	ReverseStrand
+	// Classifiers using canonical k-mers see both strands at once.
+	BothStrands
+)
+
+func (s Strand) String() string {
+	return [...]string{"+", "-", "+/-"}[s]
+}
+
+// A Prediction is the class assigned to a sequence, together with the 
+// confidence of the assignment (from 0 to 1). Lineage is the full lineage
+// of the class, and RankConfidence the confidence at each of its ranks.
+// Score tells how well the sequence matches the class (the log score for 
+// naive Bayes, the similarity of the nearest species for kNN), and Strand 
+// which strand of the sequence it was predicted from. Neighbours holds the
+// nearest species of a kNN prediction, the nearest first, and Votes the 
+// votes of the neighbours for their classes, the most voted first: that is
+// the predicted class, unless the lineage is voted rank by rank (see 
+// KNNClassifier.SetHierarchical()). Posteriors holds the posterior 
+// probability of every class of a naive Bayes prediction, the most 
+// probable first.
+type Prediction struct {
+	Class 			Class
+	Confidence		float64
+	Lineage			Lineage
+	RankConfidence	[NumRanks]float64
+	Score			float64
+	Strand			Strand
+	Neighbours		[]Neighbour
+	Votes			[]Vote
+	Posteriors		[]Posterior
+}
+
+// A Neighbour is a reference species found near a query: the number of 
+// words they share, their similarity and their distance (see 
+// Similarity.distance()).
+type Neighbour struct {
+	Id				string		`json:"id"`
+	Taxonomy		string		`json:"taxonomy"`
+	Class			Class		`json:"class"`
+	Shared			int			`json:"shared"`
+	Similarity		float64		`json:"similarity"`
+	Distance		float64		`json:"distance"`
+}
+
+// Threshold() returns the predicted class, or Unclassified if the confidence
+// is below the threshold. NoHit is always kept.
+func (p Prediction) Threshold(threshold float64) Class {
+	if p.Confidence < threshold && p.Class != NoHit {
+		return Unclassified
+	}
+	return p.Class
+}
+
+// Truncate() returns the predicted lineage down to the deepest rank whose
+// confidence reaches the threshold; the ranks below it are left empty.
+func (p Prediction) Truncate(threshold float64) Lineage {
+	var lineage Lineage
+	for r := 0; r < NumRanks; r++ {
+		if p.RankConfidence[r] < threshold {
+			break
+		}
+		lineage[r] = p.Lineage[r]
+	}
+	return lineage
+}
+
+// FormatLineage() writes the predicted lineage, truncated at the threshold,
+// with the confidence of every rank, e.g. "Bacteria(1.00);Firmicutes(0.97)".
+func (p Prediction) FormatLineage(threshold float64) string {
+	lineage := p.Truncate(threshold)
+	taxa := make([]string, 0, NumRanks)
+	for r, taxon := range lineage {
+		if taxon != "" {
+			taxa = append(taxa, fmt.Sprintf("%s(%.2f)", taxon, 
+				p.RankConfidence[r]))
+		}
+	}
+	return strings.Join(taxa, ";")
+}
+
+// Generate a smaller data set from full-sized SILVA_SSU.fasta, keeping the
+// records selected by a filter specification. The identity lines are parsed
+// with format, or detected with AutoFormat() if it is nil.
+// A side effect of this function is to return a slice of struct Species
+// of all species in our new data set, and a report of the records each rule
+// of the specification discarded.
+func GetNewDataSetFromFASTA(primaryFileName, dataSetName string, 
+	spec FilterSpec, format HeaderFormat) (*[]Species, *FilterReport, error) {
+	file, err := os.Open(primaryFileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	var species *[]Species
+	var report *FilterReport
+	err = createFile(dataSetName, func(outfile io.Writer) error {
+		species, report, err = ReadFASTAFile(file, outfile, spec, format)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range *species {
+		(*species)[i].Words = GenerateWords((*species)[i].Sequence, 
+			DefaultKmerConfig)
+	}
+	return species, report, nil
+}
+
+// This is a subroutine of GetNewDataSetFromFASTA(). It parses .fasta file,
+// and writes the records selected by spec to outfile (see FilterSpec). The
+// identity lines are parsed with format (AutoFormat() if nil). Balancing 
+// classes needs to read the file twice, so file must then be an io.Seeker.
+func ReadFASTAFile(file io.Reader, outfile io.Writer, spec FilterSpec, 
+	format HeaderFormat) (*[]Species, *FilterReport, error) {
+	rf, err := newRecordFilter(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	species := make([]Species, 0)
+	keep := func(s Species) error {
+		species = append(species, s)
+		return s.WriteToFile(outfile)
+	}
+	if spec.balances() {
+		seeker, isSeeker := file.(io.Seeker)
+		if !isSeeker {
+			return nil, nil, fmt.Errorf("%w: balancing classes needs a "+
+				"file that can be read twice", ErrInvalidFilter)
+		}
+		err := scanFASTA(file, format, func(s Species) error {
+			rf.collect(s)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		selected := rf.balance()
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		position := 0
+		err = scanFASTA(file, format, func(s Species) error {
+			position++
+			if !selected[position] {
+				return nil
+			}
+			return keep(s)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &species, &rf.report, nil
+	}
+	err = scanFASTA(file, format, func(s Species) error {
+		if !rf.add(s) {
+			return nil
+		}
+		return keep(s)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if spec.TargetSize > 0 {
+		for _, s := range rf.sample() {
+			if err := keep(s); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return &species, &rf.report, nil
+}
+
+// scanFASTA() parses the records of a .fasta file with taxonomy lines, and
+// passes each of them to found().
+func scanFASTA(file io.Reader, format HeaderFormat, 
+	found func(s Species) error) error {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	isFirst := true
+	s := Species{Words: make([]Word, 0)}
+	line := 0
+	for scanner.Scan() {
+		line++
+		temp_string := scanner.Text()
+		if strings.HasPrefix(temp_string, ">") {
+			if isFirst {
+				isFirst = false
+			} else {
+				if err := found(s); err != nil {
+					return err
+				}
+				s = Species{Words: make([]Word, 0)}
+			}
+			if err := s.parseHeader(temp_string, format); err != nil {
+				err.(*HeaderError).Line = line
+				return err
+			}
+		} else {
+			temp_sequence := []string{s.Sequence, temp_string}
+			s.Sequence = strings.Join(temp_sequence, "")
+		}
+	}
+	if scanner.Err() != nil {
+		return scanner.Err()
+	}
+	if !isFirst {
+		return found(s)
+	}
+	return nil
+}
+
+// This is a subroutine of ReadFASTAFile(), and it helps to parse the identity
+// line of the fasta format, and then stores it in struct Species. The format
+// of the line is detected (see AutoFormat()). It returns a *HeaderError if 
+// the line is malformed.
+func (s *Species) IdHelper(temp_string string) error {
+	return s.parseHeader(temp_string, AutoFormat)
+}
+
+// parseHeader() parses an identity line with a header format, AutoFormat()
+// if it is nil, and returns a *HeaderError if the line is malformed.
+func (s *Species) parseHeader(temp_string string, format HeaderFormat) error {
+	if format == nil {
+		format = AutoFormat
+	}
+	if !format(temp_string[1:], s) {
+		return &HeaderError{Header: temp_string}
+	}
+	return nil
+}
+
+// ReadIdHelper() parses the identity line of an arbitrary read. Reference 
+// headers are parsed as in IdHelper(); for any other header only the first
+// word is kept as the Id, and the taxonomy is left empty.
+func (s *Species) ReadIdHelper(temp_string string) {
+	if AutoFormat(temp_string[1:], s) {
+		return
+	}
+	fields := strings.Fields(temp_string[1:])
+	if len(fields) > 0 {
+		s.Id = fields[0]
+	}
+}
+
+// This function writes struct Species into a file.
+func (s *Species) WriteToFile(outfile io.Writer) error {
+	_, err := fmt.Fprintln(outfile, ">"+s.Id, s.Taxonomy)
+	if err == nil {
+		_, err = fmt.Fprintln(outfile, s.Sequence)
+	}
+	return err
+}
+
@@ -0,0 +1,55 @@
+package classifier
+
+import "testing"
+
+func TestParseLineage(t *testing.T) {
+	tests := []struct {
+		taxonomy	string
+		want		Lineage
+	}{
+		{"Bacteria;Firmicutes;Bacilli;Bacillales;Bacillaceae;Bacillus;" +
+			"Bacillus subtilis", Lineage{"Bacteria", "Firmicutes", "Bacilli",
+			"Bacillales", "Bacillaceae", "Bacillus"}},
+		{"Bacteria;Firmicutes;Bacillus;Bacillus sp.", Lineage{"Bacteria",
+			"Firmicutes", "", "", "", "Bacillus"}},
+		{"Bacteria;Proteobacteria;Gammaproteobacteria;Enterobacterales;" +
+			"Enterobacteriaceae;Klebsiella;Klebsiella;Klebsiella sp.",
+			Lineage{"Bacteria", "Proteobacteria", "Gammaproteobacteria",
+			"Enterobacterales", "Klebsiella", "Klebsiella"}},
+		{"Bacteria;Bacteria sp.", Lineage{RankDomain: "Bacteria"}},
+		{"Bacteria", Lineage{}},
+	}
+	for _, test := range tests {
+		if got := ParseLineage(test.taxonomy); got != test.want {
+			t.Errorf("ParseLineage(%q) = %v, want %v", test.taxonomy, got,
+				test.want)
+		}
+	}
+}
+
+func TestPredictionFormatLineage(t *testing.T) {
+	p := Prediction{
+		Class:			"Bacillus",
+		Confidence:		0.6,
+		Lineage:		Lineage{"Bacteria", "Firmicutes", "Bacilli",
+			"Bacillales", "Bacillaceae", "Bacillus"},
+		RankConfidence:	[NumRanks]float64{1, 1, 0.95, 0.9, 0.7, 0.6},
+	}
+	tests := []struct {
+		threshold	float64
+		want		string
+	}{
+		{0, "Bacteria(1.00);Firmicutes(1.00);Bacilli(0.95);" +
+			"Bacillales(0.90);Bacillaceae(0.70);Bacillus(0.60)"},
+		{0.8, "Bacteria(1.00);Firmicutes(1.00);Bacilli(0.95);" +
+			"Bacillales(0.90)"},
+		{0.96, "Bacteria(1.00);Firmicutes(1.00)"},
+		{1.5, ""},
+	}
+	for _, test := range tests {
+		if got := p.FormatLineage(test.threshold); got != test.want {
+			t.Errorf("threshold %g: got %q, want %q", test.threshold, got,
+				test.want)
+		}
+	}
+}
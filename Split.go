@@ -0,0 +1,167 @@
+package classifier
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// SplitOptions control how a data set is split into training, test and
+// validation sets. Test and Validation are the fractions of the species put
+// in the test and validation sets (Validation may be 0), the rest being
+// used for training. With Stratify, every class is split in these
+// proportions. With HoldoutClades, whole classes (genera) are split instead
+// of species, so that the test and validation sets only hold genera the
+// training set has never seen; Stratify is then ignored.
+type SplitOptions struct {
+	Test			float64
+	Validation		float64
+	Seed			int64
+	Stratify		bool
+	HoldoutClades	bool
+}
+
+var DefaultSplitOptions = SplitOptions{Test: 0.2, Seed: 1, Stratify: true}
+
+// A DataSplit holds the data sets a data set was split into. Validation is
+// nil if no validation set was asked for.
+type DataSplit struct {
+	Train			*RawData
+	Test			*RawData
+	Validation		*RawData
+}
+
+// Split() splits a data set. Species with identical sequences (case, U and
+// T, and alignment gaps aside) are always put in the same set, so that no
+// sequence is both learned and tested. The split only depends on the data
+// set and the seed. Classes with few species may not have any in the test
+// or validation set.
+func Split(d *RawData, opts SplitOptions) (*DataSplit, error) {
+	if opts.Test < 0 || opts.Validation < 0 || opts.Test+opts.Validation >= 1 {
+		return nil, fmt.Errorf("classifier: cannot split %g for testing "+
+			"and %g for validation", opts.Test, opts.Validation)
+	}
+	units := splitUnits(d, opts.HoldoutClades)
+	r := rand.New(rand.NewSource(opts.Seed))
+	var sets [3][]int
+	deal := func(group [][]int) {
+		r.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+		numOfTest := int(math.Round(opts.Test * float64(len(group))))
+		numOfValidation := int(math.Round(opts.Validation *
+			float64(len(group))))
+		if numOfTest+numOfValidation > len(group) {
+			numOfValidation = len(group) - numOfTest
+		}
+		for i, unit := range group {
+			set := 0
+			if i < numOfTest {
+				set = 1
+			} else if i < numOfTest+numOfValidation {
+				set = 2
+			}
+			sets[set] = append(sets[set], unit...)
+		}
+	}
+	if opts.Stratify && !opts.HoldoutClades {
+		// A unit belongs to the class of its first species.
+		byClass := make(map[Class][][]int)
+		for _, unit := range units {
+			class := d.species[unit[0]].Class
+			byClass[class] = append(byClass[class], unit)
+		}
+		classes := make([]Class, 0, len(byClass))
+		for class := range byClass {
+			classes = append(classes, class)
+		}
+		sort.Slice(classes, func(i, j int) bool {
+			return classes[i] < classes[j]
+		})
+		for _, class := range classes {
+			deal(byClass[class])
+		}
+	} else {
+		deal(units)
+	}
+	split := new(DataSplit)
+	for _, set := range sets {
+		sort.Ints(set)
+	}
+	split.Train, split.Test = d.subset(sets[0]), d.subset(sets[1])
+	if opts.Validation > 0 {
+		split.Validation = d.subset(sets[2])
+	}
+	return split, nil
+}
+
+// splitUnits() groups the ids of the species that must go to the same set:
+// those with identical sequences and, for clade holdout, those of the same
+// class. Groups are sorted by their first id.
+func splitUnits(d *RawData, byClass bool) [][]int {
+	parent := make([]int, len(d.species))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		i, j = find(i), find(j)
+		if i < j {
+			parent[j] = i
+		} else if j < i {
+			parent[i] = j
+		}
+	}
+	firstOfSequence := make(map[[16]byte]int)
+	firstOfClass := make(map[Class]int)
+	for id, spe := range d.species {
+		hash := hashSequence(spe.Sequence)
+		if first, isExist := firstOfSequence[hash]; isExist {
+			union(first, id)
+		} else {
+			firstOfSequence[hash] = id
+		}
+		if !byClass {
+			continue
+		}
+		if first, isExist := firstOfClass[spe.Class]; isExist {
+			union(first, id)
+		} else {
+			firstOfClass[spe.Class] = id
+		}
+	}
+	units := make([][]int, 0)
+	index := make(map[int]int)
+	for id := range d.species {
+		root := find(id)
+		i, isExist := index[root]
+		if !isExist {
+			i = len(units)
+			index[root] = i
+			units = append(units, nil)
+		}
+		units[i] = append(units[i], id)
+	}
+	return units
+}
+
+// WriteRawData() writes the species of a data set in the format read by
+// ReadRawData().
+func WriteRawData(w io.Writer, d *RawData) error {
+	writer := bufio.NewWriter(w)
+	for _, spe := range d.species {
+		if err := spe.WriteToFile(writer); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,114 @@
+package classifier
+
+import (
+	"reflect"
+	"testing"
+)
+
+// splitIds() returns the Ids of the species of every set of a split.
+func splitIds(split *DataSplit) [][]string {
+	sets := [][]string{nil, nil, nil}
+	for i, d := range []*RawData{split.Train, split.Test, split.Validation} {
+		if d == nil {
+			continue
+		}
+		for _, spe := range d.Species() {
+			sets[i] = append(sets[i], spe.Id)
+		}
+	}
+	return sets
+}
+
+func TestSplit(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	// A copy of a species, in lower case, must go to the set of the
+	// original.
+	species := make([]Species, 0)
+	for _, spe := range d.Species() {
+		species = append(species, *spe)
+	}
+	duplicate := newTestSpecies("duplicate", species[0].Class,
+		lowerCase(species[0].Sequence), DefaultKmerConfig)
+	species = append(species, duplicate, queries[0])
+	d = NewRawData(&species, DefaultKmerConfig)
+	tests := []struct {
+		name	string
+		opts	SplitOptions
+	}{
+		{"default", DefaultSplitOptions},
+		{"validation", SplitOptions{Test: 0.2, Validation: 0.1, Seed: 2,
+			Stratify: true}},
+		{"unstratified", SplitOptions{Test: 0.3, Seed: 3}},
+		{"holdout", SplitOptions{Test: 0.25, Validation: 0.25, Seed: 4,
+			HoldoutClades: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			split, err := Split(d, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (split.Validation != nil) != (test.opts.Validation > 0) {
+				t.Errorf("validation set %v", split.Validation)
+			}
+			sets := splitIds(split)
+			setOf := make(map[string]int)
+			for i, ids := range sets {
+				for _, id := range ids {
+					if _, isExist := setOf[id]; isExist {
+						t.Errorf("%s is in two sets", id)
+					}
+					setOf[id] = i
+				}
+			}
+			if len(setOf) != len(species) {
+				t.Errorf("%d species split, want %d", len(setOf),
+					len(species))
+			}
+			if setOf["duplicate"] != setOf[species[0].Id] {
+				t.Error("identical sequences are in different sets")
+			}
+			if len(sets[1]) == 0 {
+				t.Error("empty test set")
+			}
+			if test.opts.HoldoutClades {
+				classOf := make(map[Class]int)
+				for _, spe := range species {
+					set, isExist := classOf[spe.Class]
+					if isExist && set != setOf[spe.Id] {
+						t.Errorf("%s is in several sets", spe.Class)
+					}
+					classOf[spe.Class] = setOf[spe.Id]
+				}
+			}
+			again, err := Split(d, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(splitIds(again), sets) {
+				t.Error("the same seed gives another split")
+			}
+		})
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	d, _ := unbalancedData(DefaultKmerConfig)
+	for _, opts := range []SplitOptions{{Test: -0.1}, {Test: 0.5,
+		Validation: 0.5}, {Test: 1}} {
+		if _, err := Split(d, opts); err == nil {
+			t.Errorf("Split(%+v) did not fail", opts)
+		}
+	}
+}
+
+// lowerCase() returns a sequence in lower case.
+func lowerCase(sequence string) string {
+	bases := []byte(sequence)
+	for i, b := range bases {
+		if b >= 'A' && b <= 'Z' {
+			bases[i] = b - 'A' + 'a'
+		}
+	}
+	return string(bases)
+}
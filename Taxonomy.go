@@ -0,0 +1,197 @@
+package classifier
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A TaxonomyMap holds the lineages of sequences whose FASTA headers only
+// carry their ID, read from a separate taxonomy file, as the QIIME 2 and
+// DADA2 reference exports have. Its Format() method is the header format of
+// the sequences, and Check() reports the IDs found in only one of the files.
+type TaxonomyMap struct {
+	species		map[string]Species
+	seen		map[string]bool
+	missing		map[string]bool
+}
+
+// LoadTaxonomyMap() reads a taxonomy file (see ReadTaxonomyMap()).
+func LoadTaxonomyMap(fileName string, format HeaderFormat) (*TaxonomyMap,
+	error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadTaxonomyMap(file, format)
+}
+
+// ReadTaxonomyMap() reads a tab-separated taxonomy file with one sequence
+// per line: its ID, its taxonomy and optionally more columns, such as the
+// confidence of QIIME 2. A first line starting with "Feature ID", empty
+// lines and lines starting with "#" are skipped. The taxonomy is parsed
+// with format (AutoFormat() if nil) as if it followed the ID in a header,
+// the spaces around ";" left out, so "k__Bacteria; p__Firmicutes; ..." and
+// "Bacteria;Firmicutes;...;Genus;" both work. Taxonomies without rank 
+// prefixes must hold the six ranks from domain to genus, optionally 
+// followed by the species, and the "D_0__" to "D_6__" prefixes of the SILVA
+// 132 QIIME release give the rank of each taxon (see positionalTaxa()). A 
+// line that cannot be parsed, or a repeated ID, is reported as 
+// ErrMalformedTaxonomy.
+func ReadTaxonomyMap(file io.Reader, format HeaderFormat) (*TaxonomyMap,
+	error) {
+	if format == nil {
+		format = AutoFormat
+	}
+	t := &TaxonomyMap{
+		make(map[string]Species),
+		make(map[string]bool),
+		make(map[string]bool),
+	}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		temp_string := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(temp_string) == "" ||
+			strings.HasPrefix(temp_string, "#") ||
+			(line == 1 && strings.HasPrefix(temp_string, "Feature ID")) {
+			continue
+		}
+		fields := strings.Split(temp_string, "\t")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%w at line %d: no tab-separated "+
+				"taxonomy", ErrMalformedTaxonomy, line)
+		}
+		id := strings.TrimSpace(fields[0])
+		if _, isExist := t.species[id]; isExist {
+			return nil, fmt.Errorf("%w at line %d: ID %q is repeated",
+				ErrMalformedTaxonomy, line, id)
+		}
+		taxa := strings.Split(fields[1], ";")
+		for i := range taxa {
+			taxa[i] = strings.TrimSpace(taxa[i])
+		}
+		taxa, isPositional := positionalTaxa(taxa)
+		if !isPositional {
+			return nil, fmt.Errorf("%w at line %d: cannot tell the ranks "+
+				"of %q (without rank prefixes, the six ranks from domain to "+
+				"genus are needed, optionally followed by the species)", 
+				ErrMalformedTaxonomy, line, fields[1])
+		}
+		var s Species
+		if id == "" || !format(id+" "+strings.Join(taxa, ";"), &s) {
+			return nil, fmt.Errorf("%w at line %d: %q",
+				ErrMalformedTaxonomy, line, temp_string)
+		}
+		t.species[id] = s
+	}
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+	return t, nil
+}
+
+// positionalTaxa() returns the taxa of a taxonomy as the six ranks from 
+// domain to genus followed by the species, which may be empty, so that a 
+// lineage without species is not read as if its genus were the species. 
+// The "D_n__" prefixes of the SILVA 132 QIIME release place each taxon at 
+// rank n, and are removed. Taxonomies without prefixes must have six or 
+// seven taxa, a trailing ";" aside, or false is returned. Taxonomies with 
+// other rank prefixes (such as "k__") are left to the header format.
+func positionalTaxa(taxa []string) ([]string, bool) {
+	if len(taxa) > 1 && taxa[len(taxa)-1] == "" {
+		taxa = taxa[:len(taxa)-1]
+	}
+	if strings.HasPrefix(taxa[0], "D_0__") {
+		ranked := make([]string, NumRanks+1)
+		for _, taxon := range taxa {
+			if len(taxon) < 5 || taxon[:2] != "D_" || taxon[3:5] != "__" || 
+				taxon[2] < '0' || taxon[2] > '0'+byte(NumRanks) {
+				return nil, false
+			}
+			ranked[taxon[2]-'0'] = taxon[5:]
+		}
+		return ranked, true
+	}
+	if strings.Contains(taxa[0], "__") {
+		return taxa, true
+	}
+	if len(taxa) == NumRanks {
+		return append(taxa, ""), true
+	}
+	return taxa, len(taxa) == NumRanks+1
+}
+
+// Format() looks the first word of a header up in the taxonomy file, and
+// stores its lineage in s. A header whose ID is not in the file still
+// passes, without lineage, and is reported by Check() once the sequences
+// are read, so that all the missing IDs are reported at once.
+func (t *TaxonomyMap) Format(header string, s *Species) bool {
+	id, _ := splitHeader(header)
+	t.seen[id] = true
+	entry, isExist := t.species[id]
+	if !isExist {
+		t.missing[id] = true
+		s.Id = id
+		return true
+	}
+	*s = entry
+	s.Id = id
+	return true
+}
+
+// Check() returns an error wrapping ErrMissingTaxonomy if some sequences
+// read with Format() are not in the taxonomy file, or some IDs of the
+// taxonomy file were not among them.
+func (t *TaxonomyMap) Check() error {
+	return t.check(true)
+}
+
+// CheckSequences() is Check() for sequences that are only part of those the
+// taxonomy file describes, such as a test set: only the sequences missing 
+// from the taxonomy file are reported.
+func (t *TaxonomyMap) CheckSequences() error {
+	return t.check(false)
+}
+
+func (t *TaxonomyMap) check(isComplete bool) error {
+	missing := make([]string, 0, len(t.missing))
+	for id := range t.missing {
+		missing = append(missing, id)
+	}
+	unused := make([]string, 0)
+	for id := range t.species {
+		if isComplete && !t.seen[id] {
+			unused = append(unused, id)
+		}
+	}
+	problems := make([]string, 0, 2)
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d sequence IDs are not "+
+			"in the taxonomy file (%s)", len(missing), listIds(missing)))
+	}
+	if len(unused) > 0 {
+		problems = append(problems, fmt.Sprintf("%d IDs of the taxonomy "+
+			"file are not among the sequences (%s)", len(unused),
+			listIds(unused)))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrMissingTaxonomy,
+		strings.Join(problems, "; "))
+}
+
+// listIds() sorts IDs, and joins the first five of them for messages.
+func listIds(ids []string) string {
+	sort.Strings(ids)
+	if len(ids) > 5 {
+		return strings.Join(ids[:5], ", ") + ", ..."
+	}
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,122 @@
+package classifier
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadTaxonomyMap(t *testing.T) {
+	tests := []struct {
+		name		string
+		taxonomy	string
+		lineage		Lineage
+		species		string
+	}{
+		{"plain with species", "Bacteria;Firmicutes;Bacilli;Bacillales;" +
+			"Bacillaceae;Bacillus;Bacillus subtilis", Lineage{"Bacteria",
+			"Firmicutes", "Bacilli", "Bacillales", "Bacillaceae", "Bacillus"},
+			"Bacillus subtilis"},
+		{"plain without species", "Bacteria;Firmicutes;Bacilli;Bacillales;" +
+			"Bacillaceae;Bacillus", Lineage{"Bacteria", "Firmicutes",
+			"Bacilli", "Bacillales", "Bacillaceae", "Bacillus"}, ""},
+		{"plain with trailing separator", "Bacteria;Firmicutes;Bacilli;" +
+			"Bacillales;Bacillaceae;Bacillus;", Lineage{"Bacteria",
+			"Firmicutes", "Bacilli", "Bacillales", "Bacillaceae", "Bacillus"},
+			""},
+		{"greengenes", "k__Bacteria; p__Firmicutes; c__Bacilli; " +
+			"o__Bacillales; f__Bacillaceae; g__Bacillus; s__subtilis",
+			Lineage{"Bacteria", "Firmicutes", "Bacilli", "Bacillales",
+			"Bacillaceae", "Bacillus"}, "subtilis"},
+		{"silva 132", "D_0__Bacteria;D_1__Firmicutes;D_2__Bacilli;" +
+			"D_3__Bacillales;D_4__Bacillaceae;D_5__Bacillus",
+			Lineage{"Bacteria", "Firmicutes", "Bacilli", "Bacillales",
+			"Bacillaceae", "Bacillus"}, ""},
+		{"silva 132 to the family", "D_0__Bacteria;D_1__Firmicutes;" +
+			"D_2__Bacilli;D_3__Bacillales;D_4__Bacillaceae",
+			Lineage{"Bacteria", "Firmicutes", "Bacilli", "Bacillales",
+			"Bacillaceae", "Bacillaceae_unclassified"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := "Feature ID\tTaxon\tConfidence\nAB1\t" + test.taxonomy +
+				"\t0.99\n"
+			tm, err := ReadTaxonomyMap(strings.NewReader(file), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s Species
+			if !tm.Format("AB1 description", &s) {
+				t.Fatal("AB1 not found")
+			}
+			if s.Id != "AB1" || s.Lineage != test.lineage ||
+				s.Name != test.species {
+				t.Errorf("got %q %v %q, want AB1 %v %q", s.Id, s.Lineage,
+					s.Name, test.lineage, test.species)
+			}
+			if err := tm.Check(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestReadTaxonomyMapMalformed(t *testing.T) {
+	tests := []struct {
+		name	string
+		file	string
+	}{
+		{"no taxonomy", "AB1\n"},
+		{"too few ranks", "AB1\tBacteria;Firmicutes;Bacillus\n"},
+		{"too many ranks", "AB1\tBacteria;Firmicutes;Bacilli;Bacillales;" +
+			"Bacillaceae;Bacillus;Bacillus subtilis;strain 168\n"},
+		{"bad rank prefix", "AB1\tD_0__Bacteria;D_9__Firmicutes\n"},
+		{"repeated ID", "AB1\tk__Bacteria;g__Bacillus\n" +
+			"AB1\tk__Bacteria;g__Bacillus\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadTaxonomyMap(strings.NewReader(test.file), nil)
+			if !errors.Is(err, ErrMalformedTaxonomy) {
+				t.Errorf("got %v, want ErrMalformedTaxonomy", err)
+			}
+		})
+	}
+}
+
+func TestTaxonomyMapCheck(t *testing.T) {
+	file := "AB1\tk__Bacteria;g__Bacillus\nAB2\tk__Bacteria;g__Listeria\n"
+	tests := []struct {
+		name			string
+		headers			[]string
+		complete		bool
+		sequencesOnly	bool
+	}{
+		{"all", []string{"AB1", "AB2"}, true, true},
+		{"subset", []string{"AB1"}, false, true},
+		{"missing", []string{"AB1", "AB2", "AB3"}, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tm, err := ReadTaxonomyMap(strings.NewReader(file), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, header := range test.headers {
+				var s Species
+				if !tm.Format(header, &s) || s.Id != header {
+					t.Fatalf("%s not passed", header)
+				}
+			}
+			if err := tm.Check(); (err == nil) != test.complete ||
+				(err != nil && !errors.Is(err, ErrMissingTaxonomy)) {
+				t.Errorf("Check() = %v", err)
+			}
+			if err := tm.CheckSequences(); (err == nil) !=
+				test.sequencesOnly || (err != nil &&
+				!errors.Is(err, ErrMissingTaxonomy)) {
+				t.Errorf("CheckSequences() = %v", err)
+			}
+		})
+	}
+}
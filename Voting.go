@@ -0,0 +1,112 @@
+package classifier
+
+import (
+	"sort"
+)
+
+// VotingScheme tells how much the vote of each neighbour of a kNN
+// prediction weighs. It is stored with the classifier.
+type VotingScheme int
+
+const (
+	// Every neighbour has one vote.
+	UniformVote VotingScheme = iota
+	// A neighbour votes with the inverse of its distance to the query, see
+	// minDistance.
+	DistanceVote
+	// A neighbour votes with its similarity to the query.
+	SimilarityVote
+)
+
+var votingNames = []string{"uniform", "distance", "similarity"}
+
+func (v VotingScheme) String() string {
+	return nameOf(votingNames, int(v))
+}
+
+func (v *VotingScheme) Set(name string) error {
+	i, err := parseName(votingNames, name, "voting scheme")
+	if err == nil {
+		*v = VotingScheme(i)
+	}
+	return err
+}
+
+// The distance added before inverting it, so that a neighbour identical to
+// the query gets a large but finite weight.
+const minDistance = 1e-6
+
+func (v VotingScheme) weight(h *hit) float64 {
+	switch v {
+	case DistanceVote:
+		return 1 / (h.distance + minDistance)
+	case SimilarityVote:
+		return h.similarity
+	}
+	return 1
+}
+
+// A Vote is the share of a taxon in the vote of the neighbours of a kNN
+// prediction: the number of neighbours voting for it and their total
+// weight.
+type Vote struct {
+	Class		Class
+	Count		int
+	Weight		float64
+}
+
+// A tally adds up the votes for a taxon, with what is needed to break ties:
+// the summed similarity of the neighbours voting for it, and the position of
+// the nearest of them.
+type tally struct {
+	Vote
+	similarity		float64
+	nearest			int
+}
+
+// elect() counts the votes of neighbours for their taxa, and returns them
+// with the winner first. The neighbours must be sorted nearest first. Ties
+// in weight go to the taxon whose neighbours have the highest summed
+// similarity, and then to the taxon of the nearest neighbour, so the result
+// never depends on the order of a map.
+func (v VotingScheme) elect(candidates []*Species, hits map[*Species]*hit,
+	taxon func(spe *Species) Class) []tally {
+	tallies := make([]tally, 0)
+	index := make(map[Class]int)
+	for i, spe := range candidates {
+		t := taxon(spe)
+		j, isExist := index[t]
+		if !isExist {
+			j = len(tallies)
+			index[t] = j
+			tallies = append(tallies, tally{Vote: Vote{Class: t}, nearest: i})
+		}
+		h := hits[spe]
+		tallies[j].Count++
+		tallies[j].Weight += v.weight(h)
+		tallies[j].similarity += h.similarity
+	}
+	sort.Slice(tallies, func(i, j int) bool {
+		a, b := tallies[i], tallies[j]
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		if a.similarity != b.similarity {
+			return a.similarity > b.similarity
+		}
+		return a.nearest < b.nearest
+	})
+	return tallies
+}
+
+// nearer() tells if a species ranks before another as a neighbour: the more
+// similar first, and then the one with the smaller reference ID.
+func nearer(a, b *Species, hits map[*Species]*hit) bool {
+	if hits[a].similarity != hits[b].similarity {
+		return hits[a].similarity > hits[b].similarity
+	}
+	if a.Id != b.Id {
+		return a.Id < b.Id
+	}
+	return hits[a].id < hits[b].id
+}
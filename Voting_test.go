@@ -0,0 +1,126 @@
+package classifier
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// neighbours() returns species of the given classes, in order, with hits of
+// the given similarities.
+func neighbours(classes []Class, similarities []float64) ([]*Species,
+	map[*Species]*hit) {
+	candidates := make([]*Species, len(classes))
+	hits := make(map[*Species]*hit, len(classes))
+	for i, class := range classes {
+		candidates[i] = &Species{Id: fmt.Sprintf("%s.%d", class, i),
+			Class: class}
+		hits[candidates[i]] = &hit{id: int32(i),
+			similarity: similarities[i], distance: 1 - similarities[i]}
+	}
+	return candidates, hits
+}
+
+func TestElect(t *testing.T) {
+	tests := []struct {
+		name			string
+		voting			VotingScheme
+		classes			[]Class
+		similarities	[]float64
+		want			[]Vote
+	}{
+		{"most votes", UniformVote, []Class{"A", "B", "B"},
+			[]float64{0.9, 0.5, 0.5}, []Vote{{"B", 2, 2}, {"A", 1, 1}}},
+		{"tie broken by similarity", UniformVote, []Class{"B", "A"},
+			[]float64{0.5, 0.9}, []Vote{{"A", 1, 1}, {"B", 1, 1}}},
+		{"tie broken by the nearest", UniformVote, []Class{"B", "A"},
+			[]float64{0.5, 0.5}, []Vote{{"B", 1, 1}, {"A", 1, 1}}},
+		{"weighted", SimilarityVote, []Class{"A", "B", "B"},
+			[]float64{0.9, 0.4, 0.4}, []Vote{{"A", 1, 0.9},
+			{"B", 2, 0.8}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			candidates, hits := neighbours(test.classes, test.similarities)
+			tallies := test.voting.elect(candidates, hits,
+				func(spe *Species) Class { return spe.Class })
+			got := make([]Vote, len(tallies))
+			for i, t := range tallies {
+				got[i] = t.Vote
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNearer(t *testing.T) {
+	a := &Species{Id: "a"}
+	b := &Species{Id: "b"}
+	twin := &Species{Id: "a"}
+	hits := map[*Species]*hit{
+		a:		{id: 1, similarity: 0.5},
+		b:		{id: 0, similarity: 0.5},
+		twin:	{id: 2, similarity: 0.5},
+	}
+	tests := []struct {
+		name	string
+		x, y	*Species
+		want	bool
+	}{
+		{"smaller ID", a, b, true},
+		{"larger ID", b, a, false},
+		{"same ID, learned first", a, twin, true},
+		{"same ID, learned last", twin, a, false},
+		{"itself", a, a, false},
+	}
+	for _, test := range tests {
+		if got := nearer(test.x, test.y, hits); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+	hits[b].similarity = 0.6
+	if !nearer(b, a, hits) || nearer(a, b, hits) {
+		t.Error("the more similar species does not rank first")
+	}
+}
+
+// Unless the lineage is voted rank by rank, the predicted class is the 
+// first of the votes.
+func TestPredictedClassWinsVote(t *testing.T) {
+	d, queries := unbalancedData(DefaultKmerConfig)
+	kc, err := KNNLearnData(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, voting := range []VotingScheme{UniformVote, DistanceVote,
+		SimilarityVote} {
+		kc.SetVoting(voting)
+		for _, q := range queries {
+			for _, k := range []int{1, 3, 5, 10} {
+				p, err := kc.KNNPredictLineage(q.Words, k)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if p.Class != p.Votes[0].Class {
+					t.Errorf("%s, %s, k = %d: predicted %s, votes %v", 
+						voting, q.Id, k, p.Class, p.Votes)
+				}
+			}
+		}
+	}
+}
+
+func TestVotingSchemeFlag(t *testing.T) {
+	for _, name := range votingNames {
+		var v VotingScheme
+		if err := v.Set(name); err != nil || v.String() != name {
+			t.Errorf("Set(%q) gives %s, %v", name, v, err)
+		}
+	}
+	var v VotingScheme
+	if err := v.Set("majority"); err == nil {
+		t.Error("Set(\"majority\") did not fail")
+	}
+}
@@ -0,0 +1,738 @@
+// Command classifier learns naive Bayes and kNN classifiers of 16S rRNA 
+// sequences, and classifies sequences with them. See README.txt for the 
+// commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DevinZhu123/classifier"
+)
+
+func main() {
+	if len(os.Args) < 3 {
+		log.Fatal("Error: there were not enough parameters!")
+	}
+	if os.Args[1] == "ParseFile" || os.Args[1] == "TransformFile" {
+		runParseFile(os.Args[1], os.Args[2:])
+	} else if isBackend(os.Args[1]) {
+		if os.Args[2] == "learn" {
+			runLearn(os.Args[1], os.Args[3:])
+		} else if os.Args[2] == "crossvalidation" {
+			runCrossValidation(os.Args[1], os.Args[3:])
+		} else if os.Args[2] == "predict" {
+			runPredict(os.Args[1], os.Args[3:])
+		} else if os.Args[1] == "NBC" && os.Args[2] == "merge" {
+			runMerge(os.Args[3:])
+		} else if os.Args[1] == "NBC" && os.Args[2] == "unlearn" {
+			runUnlearn(os.Args[3:])
+		} else if os.Args[1] == "KNN" && os.Args[2] == "neighbours" {
+			runNeighbours(os.Args[3:])
+		} else if os.Args[1] == "KNN" {
+			if len(os.Args) != 5 {
+				log.Fatal("Error: wrong number of parameters for running "+
+						"kNN classifier!")
+			}
+			c := learnClassifier("KNN", os.Args[2], "")
+			check(configure(c, classifier.DefaultBootstrapOptions, 
+				parseK(os.Args[3])))
+			p, err := c.Predict(classifier.GenerateFeatures(os.Args[4], 
+				c.Config()))
+			check(err)
+			fmt.Println("kNN classifier prediction:", p.Class)
+		} else {
+			log.Fatal("Error: wrong command for ", describe(os.Args[1]), 
+				" classifier!")
+		}
+	} else if os.Args[1] == "ERT" {
+		runERT(os.Args[2:])
+	} else if os.Args[1] == "NBKNN" {
+		if len(os.Args) != 4 && len(os.Args) != 5 {
+			log.Fatal("Error: wrong number of parameters for running" +
+				"both classifiers!")
+		}
+		s := os.Args[3]
+		trainDataSet := ""
+		if len(os.Args) == 5 {
+			trainDataSet = os.Args[4]
+		}
+		k := parseK(os.Args[2])
+		e := getEnsemble([]string{"NBC", "KNN"}, trainDataSet, "", false,
+			classifier.DefaultBootstrapOptions, k)
+		predictions, err := e.Predict(s, false)
+		check(err)
+		for i, p := range predictions {
+			fmt.Println(describe(e[i].Name()), "classifier prediction:", 
+				p.Class)
+		}
+	} else if os.Args[1] == "classify" {
+		runClassify(os.Args[2:])
+	} else if os.Args[1] == "split" {
+		runSplit(os.Args[2:])
+	} else {
+		log.Fatal("Wrong command!")
+	}
+
+}
+
+// runParseFile() handles the "ParseFile" command, also called 
+// "TransformFile":
+//   ParseFile [-domains d,...] [-include p,...] [-exclude p,...] 
+//             [-minlength n] [-maxambiguous f] [-every n | -target n] 
+//             [-dereplicate] [-maxperclass n] [-minperclass n] [-seed s] 
+//             [-format f] [-taxonomy file] OriginalFileName NewDataSetName
+// The class counts are written to NewDataSetName.classes.tsv.
+func runParseFile(command string, args []string) {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	spec := classifier.DefaultFilterSpec
+	domains := fs.String("domains", strings.Join(spec.Domains, ","), 
+		"comma-separated domains kept (empty for all)")
+	include := fs.String("include", "", "comma-separated taxon patterns, "+
+		"one of which records must match")
+	exclude := fs.String("exclude", strings.Join(spec.Exclude, ","), 
+		"comma-separated taxon patterns records must not match")
+	fs.IntVar(&spec.MinLength, "minlength", spec.MinLength, 
+		"minimum number of bases")
+	fs.Float64Var(&spec.MaxAmbiguous, "maxambiguous", spec.MaxAmbiguous, 
+		"maximum fraction of ambiguous bases")
+	fs.IntVar(&spec.SampleEvery, "every", spec.SampleEvery, 
+		"keep every n-th record passing the other rules")
+	fs.IntVar(&spec.TargetSize, "target", spec.TargetSize, 
+		"keep this many records passing the other rules, drawn at random "+
+		"(replaces -every)")
+	fs.BoolVar(&spec.Dereplicate, "dereplicate", spec.Dereplicate, 
+		"keep only the first of identical sequences")
+	fs.IntVar(&spec.MaxPerClass, "maxperclass", spec.MaxPerClass, 
+		"maximum number of records kept per class")
+	fs.IntVar(&spec.MinPerClass, "minperclass", spec.MinPerClass, 
+		"minimum number of records kept per class, where available")
+	fs.Int64Var(&spec.Seed, "seed", spec.Seed, "seed of the random draws")
+	formatName := fs.String("format", "auto", "format of the identity "+
+		"lines, or of the taxonomy file: "+
+		strings.Join(classifier.HeaderFormats(), ", "))
+	taxonomyFile := addTaxonomyFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		log.Fatal("Error: wrong number of parameters for parsing file!")
+	}
+	spec.Domains = splitList(*domains)
+	spec.Include = splitList(*include)
+	spec.Exclude = splitList(*exclude)
+	isSet := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { isSet[f.Name] = true })
+	if (isSet["target"] || isSet["maxperclass"] || isSet["minperclass"]) && 
+		!isSet["every"] {
+		spec.SampleEvery = 1
+	}
+	format, err := classifier.LookupHeaderFormat(*formatName)
+	check(err)
+	var t *classifier.TaxonomyMap
+	if *taxonomyFile != "" {
+		t, err = classifier.LoadTaxonomyMap(*taxonomyFile, format)
+		check(err)
+		format = t.Format
+	}
+	_, report, err := classifier.GetNewDataSetFromFASTA(fs.Arg(0), 
+		fs.Arg(1), spec, format)
+	check(err)
+	if t != nil {
+		check(t.Check())
+	}
+	classifier.WriteFilterReport(os.Stdout, report)
+	summaryFile, err := os.Create(fs.Arg(1) + ".classes.tsv")
+	check(err)
+	check(classifier.WriteClassSummary(summaryFile, report))
+	check(summaryFile.Close())
+	fmt.Println("Class counts written to", fs.Arg(1)+".classes.tsv")
+}
+
+// runSplit() handles the "split" command:
+//   split [-test f] [-validation f] [-seed s] [-stratify=false] [-holdout]
+//         [-taxonomy file] DataSetName TrainDataSetName TestDataSetName 
+//         [ValidationDataSetName]
+func runSplit(args []string) {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	opts := classifier.DefaultSplitOptions
+	fs.Float64Var(&opts.Test, "test", opts.Test, "fraction of the species "+
+		"used for testing")
+	fs.Float64Var(&opts.Validation, "validation", opts.Validation, 
+		"fraction of the species used for validation")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed of the split")
+	fs.BoolVar(&opts.Stratify, "stratify", opts.Stratify, "split every "+
+		"genus in the same proportions")
+	fs.BoolVar(&opts.HoldoutClades, "holdout", opts.HoldoutClades, 
+		"withhold whole genera from the training set")
+	taxonomyFile := addTaxonomyFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 3 && fs.NArg() != 4 {
+		log.Fatal("Error: wrong number of parameters for splitting data set!")
+	}
+	if (fs.NArg() == 4) != (opts.Validation > 0) {
+		log.Fatal("Error: a validation data set needs both a file name and "+
+			"a fraction!")
+	}
+	d := loadRawData(fs.Arg(0), classifier.DefaultKmerConfig, *taxonomyFile, 
+		false)
+	split, err := classifier.Split(d, opts)
+	check(err)
+	sets := []*classifier.RawData{split.Train, split.Test, split.Validation}
+	for i, name := range fs.Args()[1:] {
+		file, err := os.Create(name)
+		check(err)
+		check(classifier.WriteRawData(file, sets[i]))
+		check(file.Close())
+		fmt.Println("Write", name, "successfully:", len(sets[i].Species()), 
+			"species of", len(sets[i].Classes()), "classes")
+	}
+}
+
+// addTaxonomyFlag() adds the option to read the lineages of a data set 
+// from a separate taxonomy file.
+func addTaxonomyFlag(fs *flag.FlagSet) *string {
+	return fs.String("taxonomy", "", "tab-separated file of the lineages "+
+		"of the sequence IDs (e.g. a QIIME 2 taxonomy)")
+}
+
+// addRetrainFlag() adds the flag asking to learn the naive Bayes classifier
+// from the training data set given to a command, instead of loading it.
+func addRetrainFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("retrain", false, "learn the naive Bayes classifier "+
+		"from TrainDataSetName too, instead of loading it")
+}
+
+// loadRawData() loads a data set, joining the lineages of a taxonomy file 
+// on the sequence IDs unless its name is empty. Unless isPart, every ID of
+// the taxonomy file must be in the data set; data sets that are only part 
+// of the references it describes, such as the test and training sets split
+// from them, are loaded with isPart.
+func loadRawData(dataSetName string, cfg classifier.KmerConfig, 
+	taxonomyFile string, isPart bool) *classifier.RawData {
+	if taxonomyFile == "" {
+		d, err := classifier.LoadRawData(dataSetName, cfg)
+		check(err)
+		return d
+	}
+	t, err := classifier.LoadTaxonomyMap(taxonomyFile, nil)
+	check(err)
+	d, err := classifier.LoadRawDataFormat(dataSetName, cfg, t.Format)
+	check(err)
+	if isPart {
+		check(t.CheckSequences())
+	} else {
+		check(t.Check())
+	}
+	return d
+}
+
+// splitList() splits a comma-separated list, an empty one giving none.
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+// isBackend() tells if a command names a registered classifier.
+func isBackend(name string) bool {
+	_, err := classifier.ModelFile(name)
+	return err == nil
+}
+
+// describe() returns the name of a backend as printed in messages.
+func describe(name string) string {
+	switch name {
+	case "NBC":
+		return "Naïve Bayes"
+	case "KNN":
+		return "kNN"
+	}
+	return name
+}
+
+// configure() applies the prediction options to the backends using them.
+func configure(c classifier.Classifier, opts classifier.BootstrapOptions, 
+	k int) error {
+	switch c := c.(type) {
+	case *classifier.BayesClassifier:
+		c.SetBootstrap(opts)
+	case *classifier.KNNClassifier:
+		return c.SetK(k)
+	}
+	return nil
+}
+
+// addBootstrapFlags() adds the options of the bootstrap confidence of naive
+// Bayes predictions to a command.
+func addBootstrapFlags(fs *flag.FlagSet) *classifier.BootstrapOptions {
+	opts := classifier.DefaultBootstrapOptions
+	fs.IntVar(&opts.Times, "bootstrap", opts.Times, 
+		"number of bootstrap replicates")
+	fs.Float64Var(&opts.Threshold, "confidence", opts.Threshold, 
+		"confidence below which a sequence is unclassified")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed of the bootstrap")
+	return &opts
+}
+
+// addStrandFlag() adds the option to also classify the reverse complement 
+// of a sequence.
+func addStrandFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("bothstrands", false, "also classify the reverse "+
+		"complement, and keep the better strand")
+}
+
+// addKmerFlags() adds the k-mer settings to a command that learns a 
+// classifier.
+func addKmerFlags(fs *flag.FlagSet) *classifier.KmerConfig {
+	cfg := classifier.DefaultKmerConfig
+	fs.IntVar(&cfg.K, "k", cfg.K, "length of the k-mers")
+	fs.Var(&cfg.Ambiguity, "ambiguous", 
+		"k-mers with ambiguous bases: skip or expand")
+	fs.IntVar(&cfg.MaxExpansions, "expansions", cfg.MaxExpansions, 
+		"maximum number of k-mers an ambiguous k-mer is expanded to")
+	fs.BoolVar(&cfg.Canonical, "canonical", cfg.Canonical, 
+		"use canonical k-mers, so that both strands give the same words")
+	return &cfg
+}
+
+// addBayesFlags() adds the model of the naive Bayes classifier to a 
+// command that learns one.
+func addBayesFlags(fs *flag.FlagSet) *classifier.BayesOptions {
+	opts := classifier.DefaultBayesOptions
+	fs.Var(&opts.Model, "model", "naive Bayes model: rdp, bernoulli, "+
+		"multinomial or legacy")
+	fs.Float64Var(&opts.Alpha, "alpha", opts.Alpha, "pseudocount added to "+
+		"the word counts (1 for Laplace smoothing)")
+	fs.BoolVar(&opts.Priors, "priors", opts.Priors, "weigh the classes by "+
+		"their number of training species")
+	return &opts
+}
+
+// runERT() handles the "ERT" command:
+//   ERT [-tsv file] [-confusion file] [-json file] [-taxonomy file] 
+//       [-retrain] TestDataSetName [TrainDataSetName] k
+// The taxonomy file may describe both data sets.
+func runERT(args []string) {
+	fs := flag.NewFlagSet("ERT", flag.ExitOnError)
+	tsvFile := fs.String("tsv", "", "write the per-class metrics to a TSV "+
+		"file")
+	confusionFile := fs.String("confusion", "", "write the confusion "+
+		"matrices to a TSV file")
+	jsonFile := fs.String("json", "", "write all results to a JSON file")
+	taxonomyFile := addTaxonomyFlag(fs)
+	retrain := addRetrainFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 2 && fs.NArg() != 3 {
+		log.Fatal("Error: wrong number of parameters for running" + 
+			"error rate test for both classifier!")
+	}
+	dataSetName := fs.Arg(0)
+	trainDataSet := ""
+	if fs.NArg() == 3 {
+		trainDataSet = fs.Arg(1)
+	}
+	k := parseK(fs.Arg(fs.NArg()-1))
+	opts := classifier.DefaultBootstrapOptions
+	opts.Times = 0
+	e := getEnsemble([]string{"NBC", "KNN"}, trainDataSet, *taxonomyFile, 
+		*retrain, opts, k)
+	d := loadRawData(dataSetName, e[0].Config(), *taxonomyFile, true)
+	evals, err := classifier.ERT(e, d, os.Stdout)
+	check(err)
+	exportEvaluations(*tsvFile, evals, classifier.WriteMetricsTSV)
+	exportEvaluations(*confusionFile, evals, classifier.WriteConfusionTSV)
+	exportEvaluations(*jsonFile, evals, classifier.WriteEvaluationJSON)
+}
+
+// exportEvaluations() writes evaluations to a file with the given writer,
+// unless the file name is empty.
+func exportEvaluations(fileName string, evals []*classifier.Evaluation, 
+	write func(io.Writer, []*classifier.Evaluation) error) {
+	if fileName == "" {
+		return
+	}
+	file, err := os.Create(fileName)
+	check(err)
+	check(write(file, evals))
+	check(file.Close())
+}
+
+// runCrossValidation() handles the "crossvalidation" command of a backend:
+//   NBC|KNN crossvalidation [-mode kfold|stratified|loo] [-folds n] 
+//           [-seed s] [-kmin a] [-kmax b] [-metric m] [-vote v] 
+//           [-hierarchical] [-model m] [-alpha a] [-priors] [-k length] 
+//           [-taxonomy file] TrainDataSetName
+func runCrossValidation(name string, args []string) {
+	fs := flag.NewFlagSet(name+" crossvalidation", flag.ExitOnError)
+	cfg := addKmerFlags(fs)
+	opts := classifier.DefaultCVOptions
+	if name == "NBC" {
+		opts.Bayes = *addBayesFlags(fs)
+	}
+	fs.Var(&opts.Mode, "mode", "how to make the folds: kfold, stratified "+
+		"or loo")
+	fs.IntVar(&opts.Folds, "folds", opts.Folds, "number of folds")
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed of the folds")
+	fs.IntVar(&opts.MinK, "kmin", opts.MinK, "smallest number of "+
+		"neighbours tried")
+	fs.IntVar(&opts.MaxK, "kmax", opts.MaxK, "largest number of "+
+		"neighbours tried")
+	fs.Var(&opts.Metric, "metric", "similarity of neighbours: shared, "+
+		"jaccard, containment, cosine or ani")
+	fs.Var(&opts.Voting, "vote", "weight of the votes of neighbours: "+
+		"uniform, distance or similarity")
+	fs.BoolVar(&opts.Hierarchical, "hierarchical", false, "vote the "+
+		"lineage of neighbours rank by rank")
+	taxonomyFile := addTaxonomyFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("Error: wrong number of parameters for cross-validating ", 
+			describe(name), " classifier!")
+	}
+	d := loadRawData(fs.Arg(0), *cfg, *taxonomyFile, false)
+	results, err := classifier.CrossValidate(name, d, opts)
+	check(err)
+	classifier.WriteCVTable(os.Stdout, results)
+	best := classifier.BestResult(results)
+	if best.K > 0 {
+		fmt.Println("Optimal k based on current data set:", best.K, 
+			"  mean accuracy:", best.Mean)
+	}
+}
+
+// runLearn() handles the "learn" command of a backend, e.g.:
+//   NBC learn [-append] [-k length] [-model m] [-alpha a] [-priors] 
+//             [-taxonomy file] TrainDataSetName
+//   KNN learn [-append] [-k length] [-metric m] [-vote v] [-hierarchical]
+//             [-taxonomy file] TrainDataSetName
+// With -append, the species are added to the stored classifier, whose 
+// k-mer settings are kept; its other settings only change if given.
+func runLearn(name string, args []string) {
+	fs := flag.NewFlagSet(name+" learn", flag.ExitOnError)
+	appendTo := fs.Bool("append", false, "add the species to the stored "+
+		"classifier instead of replacing it")
+	cfg := addKmerFlags(fs)
+	var metric classifier.Similarity
+	var voting classifier.VotingScheme
+	var hierarchical *bool
+	var bayesOpts *classifier.BayesOptions
+	if name == "NBC" {
+		bayesOpts = addBayesFlags(fs)
+	} else if name == "KNN" {
+		fs.Var(&metric, "metric", "similarity of neighbours: shared, "+
+			"jaccard, containment, cosine or ani")
+		fs.Var(&voting, "vote", "weight of the votes of neighbours: "+
+			"uniform, distance or similarity")
+		hierarchical = fs.Bool("hierarchical", false, "vote the lineage "+
+			"of neighbours rank by rank")
+	}
+	taxonomyFile := addTaxonomyFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("Error: wrong number of parameters for running ", 
+			describe(name), " classifier!")
+	}
+	isSet := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { isSet[f.Name] = true })
+	var c classifier.Classifier
+	var err error
+	if *appendTo {
+		if isSet["k"] || isSet["ambiguous"] || isSet["expansions"] || 
+			isSet["canonical"] {
+			log.Fatal("Error: the k-mer settings of a stored classifier "+
+				"cannot be changed!")
+		}
+		c, err = classifier.Load(name, "")
+		check(err)
+		*cfg = c.Config()
+	} else {
+		c, err = classifier.New(name, *cfg)
+		check(err)
+	}
+	d := loadRawData(fs.Arg(0), *cfg, *taxonomyFile, true)
+	switch c := c.(type) {
+	case *classifier.BayesClassifier:
+		if !*appendTo || isSet["model"] || isSet["alpha"] || 
+			isSet["priors"] {
+			check(c.SetModel(*bayesOpts))
+		}
+	case *classifier.KNNClassifier:
+		if !*appendTo || isSet["metric"] {
+			c.SetMetric(metric)
+		}
+		if !*appendTo || isSet["vote"] {
+			c.SetVoting(voting)
+		}
+		if !*appendTo || isSet["hierarchical"] {
+			c.SetHierarchical(*hierarchical)
+		}
+	}
+	check(c.Learn(d))
+	fmt.Println("Number of classes", describe(name), "classifier learned:", 
+				len(d.Classes()))
+	fmt.Println("Number of species", describe(name), "classifier learned:", 
+				len(d.Species()))
+	modelFile, err := classifier.ModelFile(name)
+	check(err)
+	check(classifier.Save(c, modelFile))
+	fmt.Println("Write", modelFile, "successfully!")
+}
+
+// runUnlearn() handles the "unlearn" command of the naive Bayes 
+// classifier:
+//   NBC unlearn [-taxonomy file] DataSetName
+// The species of the data set are removed from the stored classifier.
+func runUnlearn(args []string) {
+	fs := flag.NewFlagSet("NBC unlearn", flag.ExitOnError)
+	taxonomyFile := addTaxonomyFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("Error: wrong number of parameters for unlearning "+
+			"species!")
+	}
+	bc, err := classifier.LoadBCFromFile(classifier.DefaultBayesModel)
+	check(err)
+	d := loadRawData(fs.Arg(0), bc.Config(), *taxonomyFile, true)
+	check(bc.Unlearn(d))
+	if bc.Learned() == 0 {
+		log.Fatal("Error: unlearning every species would leave an empty "+
+			"classifier!")
+	}
+	fmt.Println("Number of species", describe("NBC"), "classifier unlearned:",
+		len(d.Species()))
+	fmt.Println("Number of species left:", bc.Learned(), "  classes left:", 
+		len(bc.Classes))
+	check(classifier.Save(bc, classifier.DefaultBayesModel))
+	fmt.Println("Write", classifier.DefaultBayesModel, "successfully!")
+}
+
+// runMerge() handles the "merge" command of the naive Bayes classifier:
+//   NBC merge [-out file] ModelFile ModelFile...
+// The saved classifiers are summed into one, written to the default model 
+// file unless -out is given.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("NBC merge", flag.ExitOnError)
+	outFile := fs.String("out", classifier.DefaultBayesModel, "file the "+
+		"merged classifier is written to")
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		log.Fatal("Error: at least two classifiers are needed for merging!")
+	}
+	bc, err := classifier.MergeBayesClassifiers(fs.Args()...)
+	check(err)
+	fmt.Println("Number of classes", describe("NBC"), "classifier merged:", 
+		len(bc.Classes))
+	fmt.Println("Number of species", describe("NBC"), "classifier merged:", 
+		bc.Learned())
+	check(classifier.Save(bc, *outFile))
+	fmt.Println("Write", *outFile, "successfully!")
+}
+
+// runPredict() handles the "predict" command of a backend:
+//   NBC predict [-confidence t] [-bootstrap n] [-seed s] [-bothstrands] 
+//               [-posteriors] [-top n] Sequence
+//   KNN predict [-confidence t] [-bothstrands] k Sequence
+func runPredict(name string, args []string) {
+	fs := flag.NewFlagSet(name+" predict", flag.ExitOnError)
+	opts := addBootstrapFlags(fs)
+	bothStrands := addStrandFlag(fs)
+	var showPosteriors *bool
+	var top *int
+	if name == "NBC" {
+		showPosteriors = fs.Bool("posteriors", false, "print the posterior "+
+			"probabilities of the classes")
+		top = fs.Int("top", 0, "number of most probable classes printed "+
+			"with -posteriors (0 for all)")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 && fs.NArg() != 2 {
+		log.Fatal("Error: wrong number of parameters for running ", 
+			describe(name), " classifier!")
+	}
+	k := classifier.DefaultNeighbours
+	if fs.NArg() == 2 {
+		k = parseK(fs.Arg(0))
+	}
+	c, err := classifier.Load(name, "")
+	check(err)
+	check(configure(c, *opts, k))
+	p, err := classifier.PredictStrands(fs.Arg(fs.NArg()-1), c, 
+		*bothStrands)
+	check(err)
+	fmt.Println(describe(name), "classifier prediction:", 
+		p.Threshold(opts.Threshold), "  confidence:", p.Confidence, 
+		"  strand:", p.Strand)
+	fmt.Println("Lineage:", p.FormatLineage(opts.Threshold))
+	if showPosteriors != nil && *showPosteriors {
+		fmt.Println("Posterior probabilities:")
+		for i, post := range p.Posteriors {
+			if *top > 0 && i == *top {
+				break
+			}
+			fmt.Printf("  %-20s  %.6g\n", post.Class, post.Probability)
+		}
+	}
+	if kc, isKNN := c.(*classifier.KNNClassifier); isKNN {
+		fmt.Println("Nearest species by", kc.Metric(), "similarity:")
+		for _, n := range p.Neighbours {
+			fmt.Printf("  %-20s  shared: %6d  similarity: %.4f  %s\n", n.Id, 
+				n.Shared, n.Similarity, n.Taxonomy)
+		}
+		fmt.Println("Votes (" + kc.Voting().String() + "):")
+		for _, v := range p.Votes {
+			fmt.Printf("  %-20s  neighbours: %3d  weight: %.4f\n", v.Class, 
+				v.Count, v.Weight)
+		}
+	}
+}
+
+// runNeighbours() handles the "neighbours" command of the kNN classifier:
+//   KNN neighbours [-json] k Sequence
+//   KNN neighbours [-json] -in InputFile k
+// It prints the k nearest reference species of the sequence, or of every 
+// read of the input file, as a TSV table or as JSON.
+func runNeighbours(args []string) {
+	fs := flag.NewFlagSet("KNN neighbours", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the neighbours as JSON")
+	inFile := fs.String("in", "", "find the neighbours of every read of a "+
+		"FASTA/FASTQ file")
+	fs.Parse(args)
+	if (*inFile == "" && fs.NArg() != 2) || (*inFile != "" && 
+		fs.NArg() != 1) {
+		log.Fatal("Error: wrong number of parameters for finding "+
+			"neighbours!")
+	}
+	k := parseK(fs.Arg(0))
+	kc, err := classifier.LoadKCFromFile(classifier.DefaultKNNModel)
+	check(err)
+	if *inFile != "" {
+		file, err := os.Open(*inFile)
+		check(err)
+		defer file.Close()
+		_, err = classifier.NeighboursFile(file, os.Stdout, kc, k, *asJSON)
+		check(err)
+		return
+	}
+	neighbours, err := kc.Nearest(classifier.GenerateFeatures(fs.Arg(1),
+		kc.Config()), k)
+	check(err)
+	results := []classifier.QueryNeighbours{
+		{Query: "query", Neighbours: neighbours}}
+	if *asJSON {
+		check(classifier.WriteNeighboursJSON(os.Stdout, results))
+	} else {
+		check(classifier.WriteNeighboursTSV(os.Stdout, results))
+	}
+}
+
+// runClassify() handles the "classify" command:
+//   classify [-confidence t] [-bootstrap n] [-seed s] [-bothstrands] 
+//            [-taxonomy file] [-retrain] NBC InputFile OutputFile 
+//            [TrainDataSetName]
+//   classify [...] KNN|NBKNN InputFile OutputFile [TrainDataSetName] k
+// Any comma-separated list of backends, such as "NBC,KNN", may be given 
+// instead of NBKNN. The output file "-" writes the results to standard 
+// output.
+func runClassify(args []string) {
+	fs := flag.NewFlagSet("classify", flag.ExitOnError)
+	opts := addBootstrapFlags(fs)
+	bothStrands := addStrandFlag(fs)
+	taxonomyFile := addTaxonomyFlag(fs)
+	retrain := addRetrainFlag(fs)
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) < 3 || len(args) > 5 {
+		log.Fatal("Error: wrong number of parameters for classifying file!")
+	}
+	names := strings.Split(args[0], ",")
+	if args[0] == "NBKNN" {
+		names = []string{"NBC", "KNN"}
+	}
+	k := classifier.DefaultNeighbours
+	rest := args[3:]
+	if len(rest) > 0 {
+		if n, err := strconv.Atoi(rest[len(rest)-1]); err == nil {
+			k = n
+			rest = rest[:len(rest)-1]
+		}
+	}
+	if len(rest) > 1 {
+		log.Fatal("Error: wrong number of parameters for classifying file!")
+	}
+	trainDataSet := ""
+	if len(rest) == 1 {
+		trainDataSet = rest[0]
+	}
+	e := getEnsemble(names, trainDataSet, *taxonomyFile, *retrain, *opts, 
+		k)
+
+	file, err := os.Open(args[1])
+	check(err)
+	defer file.Close()
+	outfile := os.Stdout
+	if args[2] != "-" {
+		outfile, err = os.Create(args[2])
+		check(err)
+		defer outfile.Close()
+	}
+	n, err := classifier.ClassifyFile(file, outfile, e, opts.Threshold, 
+		*bothStrands)
+	check(err)
+	fmt.Fprintln(os.Stderr, "Number of reads classified:", n)
+}
+
+// getEnsemble() loads the saved classifiers of the named backends, and 
+// applies the prediction options to them. When the name of a training data
+// set is given, the kNN index is built again from it, and so are the other
+// classifiers if retrain is set; they keep their stored settings otherwise.
+// The lineages of the training data set are read from the taxonomy file if
+// its name is not empty.
+func getEnsemble(names []string, trainDataSet, taxonomyFile string, 
+	retrain bool, opts classifier.BootstrapOptions, 
+	k int) classifier.Ensemble {
+	e := make(classifier.Ensemble, len(names))
+	for i, name := range names {
+		if trainDataSet == "" || name != "KNN" && !retrain {
+			c, err := classifier.Load(name, "")
+			check(err)
+			e[i] = c
+		} else {
+			e[i] = learnClassifier(name, trainDataSet, taxonomyFile)
+		}
+		check(configure(e[i], opts, k))
+	}
+	return e
+}
+
+// learnClassifier() builds a classifier of a backend from a training data 
+// set, with the default k-mer settings (see loadRawData()).
+func learnClassifier(name, trainDataSet, 
+	taxonomyFile string) classifier.Classifier {
+	d := loadRawData(trainDataSet, classifier.DefaultKmerConfig, 
+		taxonomyFile, true)
+	c, err := classifier.New(name, d.Config())
+	check(err)
+	check(c.Learn(d))
+	return c
+}
+
+func parseK(arg string) int {
+	k, err := strconv.Atoi(arg)
+	if err != nil {
+		log.Fatal("Error: wrong k for running kNN classifier"+
+		 		" prediction!")
+	}
+	return k
+}
+
+// check() stops the program with the error, if there is one.
+func check(err error) {
+	if err != nil {
+		log.Fatal("Error: ", err)
+	}
+}
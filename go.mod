@@ -0,0 +1,3 @@
+module github.com/DevinZhu123/classifier
+
+go 1.18
@@ -1,29 +1,81 @@
-package main
+package classifier
 
 import(
 	"fmt"
+	"io"
 )
 
-func ERT(bc *BayesClassifier, kc *KNNClassifier, d RawData, k int) {
-	n := len(d.species)
-	count := 1
-	BCcount := 0
-	KCcount := 0
+// ERT() tests the classifiers of an ensemble on a data set, and writes 
+// their error rates, their number of NoHit predictions, their accuracy at 
+// each rank and the precision, recall, F1 and support of every class to 
+// out. It returns the evaluation of every classifier, which can be exported
+// with WriteMetricsTSV(), WriteConfusionTSV() and WriteEvaluationJSON().
+func ERT(e Ensemble, d *RawData, out io.Writer) ([]*Evaluation, error) {
+	evals := make([]*Evaluation, len(e))
+	for i, c := range e {
+		evals[i] = NewEvaluation(c.Name())
+	}
 	for _, spe := range d.species {
-		if spe.Class == bc.BayesPredict(spe.Words) {
-			BCcount++
-		} 
-		if spe.Class == kc.KNNPredict(spe.Words, k) {
-			KCcount++
+		for i, c := range e {
+			p, err := c.Predict(featuresFor(spe, d, c.Config()))
+			if err != nil {
+				return nil, err
+			}
+			evals[i].Add(spe, p)
+		}
+	}
+	fmt.Fprint(out, "Based on test data,")
+	for i, ev := range evals {
+		if i > 0 {
+			fmt.Fprint(out, ";  ")
+		}
+		fmt.Fprint(out, " error rate of ", ev.Classifier, " classifier is ", 
+			1 - ev.Accuracy())
+	}
+	fmt.Fprintln(out)
+	for _, ev := range evals {
+		if ev.NoHits > 0 {
+			fmt.Fprintln(out, ev.NoHits, "of", ev.Matrix.Total(), 
+				"test species had no hit with", ev.Classifier, "classifier")
+		}
+	}
+	fmt.Fprintln(out, "Accuracy at each rank:")
+	fmt.Fprintf(out, "%-10s%10s", "rank", "tested")
+	for _, ev := range evals {
+		fmt.Fprintf(out, "%10s", ev.Classifier)
+	}
+	fmt.Fprintln(out)
+	for r := 0; r < NumRanks; r++ {
+		if len(evals) == 0 || evals[0].RankTested[r] == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "%-10s%10d", Rank(r), evals[0].RankTested[r])
+		for _, ev := range evals {
+			fmt.Fprintf(out, "%10.4f", ev.RankAccuracy(Rank(r)))
+		}
+		fmt.Fprintln(out)
+	}
+	for _, ev := range evals {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Per-class results of", ev.Classifier, "classifier:")
+		fmt.Fprintf(out, "%-30s%10s%10s%10s%10s\n", "class", "precision", 
+			"recall", "F1", "support")
+		metrics := append(ev.Matrix.Metrics(), ev.Matrix.MacroAverage(), 
+			ev.Matrix.MicroAverage())
+		for _, cm := range metrics {
+			fmt.Fprintf(out, "%-30s%10.4f%10.4f%10.4f%10d\n", cm.Class, 
+				cm.Precision, cm.Recall, cm.F1, cm.Support)
 		}
-		fmt.Println("# tested:", count, 
-			"   NBC successful prediction #", BCcount, 
-			"   KNN successful prediction #", KCcount)
-		count++
-	}
-	BCrate := float64(BCcount)/float64(n)
-	KCrate := float64(KCcount)/float64(n)
-	fmt.Println("Based on test data, error rata of naïve Bayes "+
-		"classifier is", 1 - BCrate, ";   error rate of kNN classifier" +
-		" is", 1 - KCrate)
-}
\ No newline at end of file
+	}
+	return evals, nil
+}
+
+// featuresFor() returns the words of a test species for a classifier, 
+// generating them again if the classifier uses other k-mer settings than 
+// the test data.
+func featuresFor(spe *Species, d *RawData, cfg KmerConfig) Features {
+	if cfg == d.config {
+		return spe.Features()
+	}
+	return GenerateFeatures(spe.Sequence, cfg)
+}